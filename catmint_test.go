@@ -1,13 +1,16 @@
 package main_test
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"catmint/hashutil"
 	"catmint/output"
@@ -206,8 +209,9 @@ func TestVerifyAllFromJSON(t *testing.T) {
 		t.Fatalf("Gagal load JSON: %v", err)
 	}
 
-	// compare prints summary; we just ensure no panic/error
-	hashutil.CompareResults(results, reference)
+	if report := hashutil.CompareResults(results, reference); !report.OK() {
+		t.Errorf("verifikasi seharusnya cocok: %+v", report)
+	}
 }
 
 func TestVerifyAllFromCSV(t *testing.T) {
@@ -228,7 +232,9 @@ func TestVerifyAllFromCSV(t *testing.T) {
 		t.Fatalf("Gagal load CSV: %v", err)
 	}
 
-	hashutil.CompareResults(results, reference)
+	if report := hashutil.CompareResults(results, reference); !report.OK() {
+		t.Errorf("verifikasi seharusnya cocok: %+v", report)
+	}
 }
 
 func TestVerifyAllFromTXT(t *testing.T) {
@@ -249,7 +255,9 @@ func TestVerifyAllFromTXT(t *testing.T) {
 		t.Fatalf("Gagal load TXT: %v", err)
 	}
 
-	hashutil.CompareResults(results, reference)
+	if report := hashutil.CompareResults(results, reference); !report.OK() {
+		t.Errorf("verifikasi seharusnya cocok: %+v", report)
+	}
 }
 
 func saveResultsForVerifyTest(t *testing.T, results []hashutil.HashResult, path, format string) {
@@ -301,3 +309,1340 @@ func saveResultsForVerifyTest(t *testing.T, results []hashutil.HashResult, path,
 		t.Fatalf("format tidak didukung: %s", format)
 	}
 }
+
+func TestCompareResultsTwoWay(t *testing.T) {
+	reference := []hashutil.HashResult{
+		{FilePath: "a.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "bbb"},
+		{FilePath: "deleted.txt", HashType: "SHA256", Hash: "ddd"},
+	}
+
+	if !hashutil.CompareResults(reference[:2], reference[:2]).OK() {
+		t.Error("seharusnya tidak ada perubahan")
+	}
+
+	// File dihapus dari disk harus terdeteksi
+	if hashutil.CompareResults(reference[:2], reference).OK() {
+		t.Error("file yang hilang dari disk seharusnya terdeteksi")
+	}
+
+	// File baru harus terdeteksi
+	actual := append([]hashutil.HashResult{}, reference[:2]...)
+	actual = append(actual, hashutil.HashResult{FilePath: "new.txt", HashType: "SHA256", Hash: "nnn"})
+	if hashutil.CompareResults(actual, reference[:2]).OK() {
+		t.Error("file baru seharusnya terdeteksi")
+	}
+
+	// File berubah harus terdeteksi
+	modified := []hashutil.HashResult{reference[0], {FilePath: "b.txt", HashType: "SHA256", Hash: "changed"}}
+	if hashutil.CompareResults(modified, reference[:2]).OK() {
+		t.Error("file yang berubah seharusnya terdeteksi")
+	}
+}
+
+func TestVerifyReportEntries(t *testing.T) {
+	reference := []hashutil.HashResult{
+		{FilePath: "a.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "bbb"},
+		{FilePath: "c.txt", HashType: "SHA256", Hash: "ccc"},
+	}
+	actual := []hashutil.HashResult{
+		{FilePath: "a.txt", HashType: "SHA256", Hash: "AAA"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "changed"},
+		{FilePath: "new.txt", HashType: "SHA256", Hash: "nnn"},
+	}
+
+	report := hashutil.CompareResults(actual, reference)
+	if len(report.Matched) != 1 || len(report.Mismatched) != 1 || len(report.Missing) != 1 || len(report.Extra) != 1 {
+		t.Fatalf("laporan tidak sesuai: %+v", report)
+	}
+	if report.Mismatched[0].Expected != "bbb" || report.Mismatched[0].Actual != "changed" {
+		t.Errorf("expected/actual mismatch tidak sesuai: %+v", report.Mismatched[0])
+	}
+	if report.Missing[0].FilePath != "c.txt" || report.Missing[0].Expected != "ccc" {
+		t.Errorf("entri missing tidak sesuai: %+v", report.Missing[0])
+	}
+
+	report.MarkUnreadable("c.txt", os.ErrPermission)
+	if len(report.Missing) != 0 || len(report.Unreadable) != 1 {
+		t.Errorf("file yang tidak bisa dibaca seharusnya hanya dilaporkan sebagai unreadable: %+v", report)
+	}
+	if report.OK() {
+		t.Error("laporan dengan perbedaan seharusnya tidak OK")
+	}
+}
+
+func TestGenerateDirHashParallelOrder(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("sub%02d", i%5))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatalf("gagal membuat direktori: %v", err)
+		}
+		createTestFileAt(t, sub, fmt.Sprintf("file%02d.txt", i), strings.Repeat("x", i*1000))
+	}
+
+	sequential, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{Workers: 1}, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+
+	var streamed []string
+	parallel, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{Workers: 8},
+		func(res hashutil.HashResult) {
+			streamed = append(streamed, res.FilePath)
+		}, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+
+	if len(parallel) != 50 || len(sequential) != 50 || len(streamed) != 50 {
+		t.Fatalf("jumlah hasil tidak sesuai: %d, %d, %d", len(sequential), len(parallel), len(streamed))
+	}
+	for i := range sequential {
+		if sequential[i].FilePath != parallel[i].FilePath || sequential[i].Hash != parallel[i].Hash || streamed[i] != sequential[i].FilePath {
+			t.Fatalf("urutan hasil berbeda pada indeks %d: %s vs %s", i, sequential[i].FilePath, parallel[i].FilePath)
+		}
+	}
+}
+
+func TestHashingHonoursCancelledContext(t *testing.T) {
+	dir := t.TempDir()
+	file := createTestFileAt(t, dir, "a.txt", "data A")
+	createTestFileAt(t, dir, "b.txt", "data B")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := hashutil.GenerateFileHashContext(ctx, file, "sha256"); !errors.Is(err, context.Canceled) {
+		t.Errorf("seharusnya context.Canceled, dapat %v", err)
+	}
+
+	results, err := hashutil.GenerateDirHashContext(ctx, dir, "sha256", hashutil.DirOptions{Workers: 2}, nil,
+		func(path string, err error) {
+			t.Errorf("pembatalan tidak boleh dilaporkan sebagai error file: %s: %v", path, err)
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("seharusnya context.Canceled, dapat %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("seharusnya tidak ada hasil, dapat %d", len(results))
+	}
+
+	if err := hashutil.VerifyFileHashContext(ctx, file, "sha256", "abc"); !errors.Is(err, context.Canceled) {
+		t.Errorf("seharusnya context.Canceled, dapat %v", err)
+	}
+
+	report, err := hashutil.VerifyDirectoryContext(ctx, dir, "sha256", nil, hashutil.DirOptions{})
+	if !errors.Is(err, context.Canceled) || !report.Interrupted || report.OK() {
+		t.Errorf("laporan seharusnya ditandai interrupted: %+v, %v", report, err)
+	}
+}
+
+func TestGenerateFileHashMultiAlgorithm(t *testing.T) {
+	file := createTestFile(t, "multi.txt", "hello world")
+
+	result, err := hashutil.GenerateFileHash(file, "sha256,md5,blake3")
+	if err != nil {
+		t.Fatalf("GenerateFileHash gagal: %v", err)
+	}
+	if len(result.Digests) != 3 || result.HashType != "SHA256" {
+		t.Fatalf("seharusnya ada 3 digest dengan SHA256 sebagai utama: %+v", result)
+	}
+	for _, alg := range []string{"sha256", "md5", "blake3"} {
+		single, err := hashutil.GenerateFileHash(file, alg)
+		if err != nil {
+			t.Fatalf("GenerateFileHash %s gagal: %v", alg, err)
+		}
+		if got, ok := result.Digest(alg); !ok || got != single.Hash {
+			t.Errorf("digest %s tidak sesuai: dapat %s, ingin %s", alg, got, single.Hash)
+		}
+	}
+
+	if _, err := hashutil.ParseAlgorithms("sha256,nope"); err == nil {
+		t.Error("seharusnya error untuk algoritma tidak didukung dalam daftar")
+	}
+
+	// Semua format output harus menyimpan dan memuat kembali semua digest
+	for _, format := range []string{"json", "csv", "txt"} {
+		path := filepath.Join(t.TempDir(), "multi."+format)
+		if err := output.SaveResultsToFile([]hashutil.HashResult{result}, path, format); err != nil {
+			t.Fatalf("gagal menyimpan %s: %v", format, err)
+		}
+		loaded, err := hashutil.LoadHashReference(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", format, err)
+		}
+		if len(loaded) != 1 || len(loaded[0].AllDigests()) != 3 {
+			t.Errorf("format %s tidak memuat semua digest: %+v", format, loaded)
+		}
+		if !hashutil.CompareResults([]hashutil.HashResult{result}, loaded).OK() {
+			t.Errorf("format %s seharusnya cocok saat verifikasi", format)
+		}
+	}
+}
+
+func TestVerifyDirectoryUsesReferenceAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	a := createTestFileAt(t, dir, "a.txt", "data A")
+	b := createTestFileAt(t, dir, "b.txt", "data B")
+	c := createTestFileAt(t, dir, "c.txt", "data C")
+
+	ra, _ := hashutil.GenerateFileHash(a, "sha512")
+	rb, _ := hashutil.GenerateFileHash(b, "md5")
+	rc, _ := hashutil.GenerateFileHash(c, "sha1")
+	rc.HashType = "" // tanpa tipe: memakai fallback
+
+	report, err := hashutil.VerifyDirectory(dir, "sha1", []hashutil.HashResult{ra, rb, rc}, hashutil.DirOptions{})
+	if err != nil {
+		t.Fatalf("VerifyDirectory gagal: %v", err)
+	}
+	if !report.OK() || len(report.Matched) != 3 {
+		t.Errorf("manifest campuran seharusnya cocok: %+v", report)
+	}
+
+	algs, err := hashutil.ReferenceAlgorithms([]hashutil.HashResult{ra, rb, rc}, "sha1")
+	if err != nil || algs != "sha1,sha512,md5" {
+		t.Errorf("daftar algoritma tidak sesuai: %q, %v", algs, err)
+	}
+
+	// Tiap file hanya di-hash dengan algoritma entrinya sendiri; file baru
+	// dengan semua algoritma supaya rename tetap terdeteksi
+	createTestFileAt(t, dir, "d.txt", "data D")
+	cache, _ := hashutil.OpenCache(filepath.Join(t.TempDir(), "cache.json"))
+	if _, err := hashutil.VerifyDirectory(dir, "sha1", []hashutil.HashResult{ra, rb, rc}, hashutil.DirOptions{Cache: cache}); err != nil {
+		t.Fatalf("VerifyDirectory dengan cache gagal: %v", err)
+	}
+	computed := make(map[string][]string)
+	for _, e := range cache.Entries() {
+		computed[filepath.Base(e.Path)] = append(computed[filepath.Base(e.Path)], e.HashType)
+	}
+	for name, want := range map[string]int{"a.txt": 1, "b.txt": 1, "c.txt": 1, "d.txt": 3} {
+		if len(computed[name]) != want {
+			t.Errorf("%s seharusnya di-hash dengan %d algoritma, didapat %v", name, want, computed[name])
+		}
+	}
+}
+
+func TestLoadCoreutilsChecksumFiles(t *testing.T) {
+	tmp := t.TempDir()
+	sha := strings.Repeat("ab", 32)
+	md := strings.Repeat("cd", 16)
+
+	gnu := filepath.Join(tmp, "SHA256SUMS")
+	content := "# comment\n" + sha + "  dir/a.txt\n" + sha + " *b.bin\n\\" + sha + "  back\\\\slash\n"
+	if err := os.WriteFile(gnu, []byte(content), 0644); err != nil {
+		t.Fatalf("gagal menulis SHA256SUMS: %v", err)
+	}
+	results, err := hashutil.LoadHashReference(gnu)
+	if err != nil {
+		t.Fatalf("gagal memuat SHA256SUMS: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("seharusnya 3 entri, dapat %d: %+v", len(results), results)
+	}
+	if results[1].FilePath != "b.bin" || results[2].FilePath != `back\slash` || results[0].HashType != "SHA256" {
+		t.Errorf("entri GNU tidak sesuai: %+v", results)
+	}
+
+	bsd := filepath.Join(tmp, "hash.txt")
+	content = "SHA256 (a (1).txt) = " + sha + "\nMD5 (c.txt) = " + md + "\n" + md + "  legacy.txt\n"
+	if err := os.WriteFile(bsd, []byte(content), 0644); err != nil {
+		t.Fatalf("gagal menulis file BSD: %v", err)
+	}
+	results, err = hashutil.LoadHashReference(bsd)
+	if err != nil {
+		t.Fatalf("gagal memuat file BSD: %v", err)
+	}
+	if len(results) != 3 || results[0].FilePath != "a (1).txt" || results[1].HashType != "MD5" || results[2].HashType != "MD5" {
+		t.Errorf("entri BSD tidak sesuai: %+v", results)
+	}
+
+	// Writer coreutils dan bsd harus bisa dibaca kembali
+	sums := []hashutil.HashResult{
+		{FilePath: filepath.Join("tmp", "test1.txt"), HashType: "SHA256", Hash: sha},
+		{FilePath: filepath.Join("tmp", "test2.txt"), HashType: "SHA256", Hash: strings.Repeat("12", 32)},
+	}
+	for _, format := range []string{"coreutils", "bsd"} {
+		path := filepath.Join(tmp, format+".sha256")
+		if err := output.SaveResultsToFile(sums, path, format); err != nil {
+			t.Fatalf("gagal menyimpan %s: %v", format, err)
+		}
+		loaded, err := hashutil.LoadHashReference(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", format, err)
+		}
+		if !hashutil.CompareResults(sums, loaded).OK() {
+			t.Errorf("format %s tidak round-trip: %+v", format, loaded)
+		}
+	}
+
+	// Format coreutils tidak boleh diam-diam membuang digest kedua
+	multi := []hashutil.HashResult{{FilePath: "a", HashType: "SHA256", Hash: sha, Digests: []hashutil.Digest{{HashType: "SHA256", Hash: sha}, {HashType: "MD5", Hash: md}}}}
+	if err := output.SaveResultsToFile(multi, filepath.Join(tmp, "multi.sha256"), "coreutils"); err == nil {
+		t.Error("coreutils dengan dua algoritma seharusnya gagal")
+	}
+
+	// Seperti sha256sum -c: setiap baris dicek, baris rusak dihitung dan dilewati
+	check := filepath.Join(tmp, "check.sha256")
+	other := strings.Repeat("ef", 32)
+	content = sha + "  a.txt\n0000  missing\nbukan baris checksum\n" + other + "  a.txt\n"
+	os.WriteFile(check, []byte(content), 0644)
+	entries, bad, err := hashutil.LoadChecksumFile(check)
+	if err != nil || bad != 2 || len(entries) != 2 || entries[1].Hash != other {
+		t.Errorf("LoadChecksumFile tidak sesuai: %v bad=%d %+v", err, bad, entries)
+	}
+	// Sebagai referensi, dua digest berbeda untuk file yang sama adalah error
+	if _, err := hashutil.LoadHashReference(check); err == nil {
+		t.Error("digest yang bertentangan seharusnya ditolak")
+	}
+}
+
+func TestPortableRelativeManifest(t *testing.T) {
+	original := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(original, "sub"), 0755); err != nil {
+		t.Fatalf("gagal membuat direktori: %v", err)
+	}
+	createTestFileAt(t, original, "a.txt", "data A")
+	createTestFileAt(t, filepath.Join(original, "sub"), "b.txt", "data B")
+
+	manifest, err := hashutil.GenerateDirHashWithOptions(original, "sha256", hashutil.DirOptions{RelativePaths: true}, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	if manifest[0].FilePath != "a.txt" || manifest[1].FilePath != "sub/b.txt" {
+		t.Fatalf("path seharusnya relatif terhadap root: %+v", manifest)
+	}
+
+	// Salinan di lokasi lain harus tetap cocok
+	copyDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(copyDir, "sub"), 0755); err != nil {
+		t.Fatalf("gagal membuat direktori: %v", err)
+	}
+	createTestFileAt(t, copyDir, "a.txt", "data A")
+	createTestFileAt(t, filepath.Join(copyDir, "sub"), "b.txt", "data B")
+
+	report, err := hashutil.VerifyDirectory(copyDir, "sha256", hashutil.RebaseResults(manifest, copyDir), hashutil.DirOptions{RelativePaths: true})
+	if err != nil || !report.OK() {
+		t.Errorf("manifest portabel seharusnya cocok: %+v, %v", report, err)
+	}
+
+	// Manifest lama dengan path mentah dapat di-anchor ulang dengan root
+	raw, err := hashutil.GenerateDirHash(original, "sha256", nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHash gagal: %v", err)
+	}
+	report, err = hashutil.VerifyDirectory(copyDir, "sha256", hashutil.RebaseResults(raw, original), hashutil.DirOptions{RelativePaths: true})
+	if err != nil || !report.OK() {
+		t.Errorf("manifest dengan root lama seharusnya cocok: %+v, %v", report, err)
+	}
+
+	if got := hashutil.NormalizePath("./sub/../sub/./b.txt"); got != "sub/b.txt" {
+		t.Errorf("NormalizePath tidak sesuai: %s", got)
+	}
+}
+
+func TestDirectoryFilters(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{".git", "build/cache", "src/pkg"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("gagal membuat direktori: %v", err)
+		}
+	}
+	createTestFileAt(t, dir, ".git/HEAD", "ref")
+	createTestFileAt(t, dir, "build/cache/obj.o", "obj")
+	createTestFileAt(t, dir, "src/main.go", "package main")
+	createTestFileAt(t, dir, "src/pkg/util.go", "package pkg")
+	createTestFileAt(t, dir, "src/pkg/util.go~", "backup")
+	createTestFileAt(t, dir, "src/pkg/keep.tmp", "keep")
+	createTestFileAt(t, dir, "notes.tmp", "tmp")
+	createTestFileAt(t, dir, "big.bin", strings.Repeat("x", 4096))
+	createTestFileAt(t, dir, hashutil.IgnoreFileName, "# build output\nbuild/\n*~\n*.tmp\n!src/**/keep.tmp\n")
+
+	names := func(opts hashutil.DirOptions) []string {
+		t.Helper()
+		opts.RelativePaths = true
+		results, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", opts, nil, nil)
+		if err != nil {
+			t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.FilePath)
+		}
+		return out
+	}
+
+	got := strings.Join(names(hashutil.DirOptions{Filter: &hashutil.Filter{SkipHidden: true, MaxSize: 1024}}), ",")
+	if got != "src/main.go,src/pkg/keep.tmp,src/pkg/util.go" {
+		t.Errorf("hasil filter tidak sesuai: %s", got)
+	}
+
+	got = strings.Join(names(hashutil.DirOptions{Filter: &hashutil.Filter{Include: []string{"src/**/*.go"}, Exclude: []string{"pkg"}}}), ",")
+	if got != "src/main.go" {
+		t.Errorf("hasil include/exclude tidak sesuai: %s", got)
+	}
+
+	got = strings.Join(names(hashutil.DirOptions{Filter: &hashutil.Filter{MaxDepth: 1, NoIgnoreFile: true}}), ",")
+	if got != hashutil.IgnoreFileName+",big.bin,notes.tmp" {
+		t.Errorf("hasil max-depth tidak sesuai: %s", got)
+	}
+
+	// File yang diabaikan di referensi tidak boleh dilaporkan hilang
+	reference := []hashutil.HashResult{{FilePath: "build/cache/obj.o", HashType: "SHA256", Hash: "abc"}}
+	all, _ := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{RelativePaths: true}, nil, nil)
+	reference = append(reference, all...)
+	report, err := hashutil.VerifyDirectory(dir, "sha256", reference, hashutil.DirOptions{RelativePaths: true})
+	if err != nil || !report.OK() {
+		t.Errorf("file yang diabaikan seharusnya tidak dilaporkan: %+v, %v", report, err)
+	}
+}
+
+func TestVerifyFileHashMismatchError(t *testing.T) {
+	file := createTestFile(t, "mismatch.txt", "data")
+
+	err := hashutil.VerifyFileHash(file, "sha256", "1234567890abcdef")
+	if !errors.Is(err, hashutil.ErrHashMismatch) {
+		t.Errorf("error mismatch seharusnya ErrHashMismatch, dapat %v", err)
+	}
+
+	err = hashutil.VerifyFileHash(filepath.Join(t.TempDir(), "missing.txt"), "sha256", "abc")
+	if err == nil || errors.Is(err, hashutil.ErrHashMismatch) {
+		t.Errorf("file yang tidak ada seharusnya error I/O, bukan mismatch: %v", err)
+	}
+}
+
+func TestDirHashCache(t *testing.T) {
+	dir := t.TempDir()
+	file := createTestFileAt(t, dir, "a.txt", "data A")
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, err := hashutil.OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache gagal: %v", err)
+	}
+	first, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{Cache: cache}, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save gagal: %v", err)
+	}
+
+	// Ubah isi file tapi pertahankan ukuran dan mtime: cache masih dipakai
+	info, _ := os.Stat(file)
+	if err := os.WriteFile(file, []byte("data B"), 0644); err != nil {
+		t.Fatalf("gagal menulis file: %v", err)
+	}
+	if err := os.Chtimes(file, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("Chtimes gagal: %v", err)
+	}
+
+	cache, err = hashutil.OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache gagal: %v", err)
+	}
+	cached, _ := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{Cache: cache}, nil, nil)
+	if cached[0].Hash != first[0].Hash || cache.Stats().Hits != 1 {
+		t.Errorf("hasil seharusnya berasal dari cache: %+v, %+v", cached, cache.Stats())
+	}
+
+	// Mode paranoid harus menghitung ulang dan menandai ketidakcocokan
+	var mismatches int
+	paranoid, _ := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{Cache: cache, Paranoid: true}, nil,
+		func(path string, err error) {
+			var mismatch *hashutil.CacheMismatchError
+			if errors.As(err, &mismatch) {
+				mismatches++
+			}
+		})
+	if mismatches != 1 || paranoid[0].Hash == first[0].Hash {
+		t.Errorf("mode paranoid seharusnya mendeteksi perubahan: %d, %+v", mismatches, paranoid)
+	}
+
+	// Entri untuk file yang dihapus harus bisa dibersihkan
+	if err := os.Remove(file); err != nil {
+		t.Fatalf("Remove gagal: %v", err)
+	}
+	if removed := cache.Prune(); removed != 1 || cache.Stats().Entries != 0 {
+		t.Errorf("Prune seharusnya menghapus 1 entri, dapat %d", removed)
+	}
+}
+
+func TestMetadataManifest(t *testing.T) {
+	dir := t.TempDir()
+	file := createTestFileAt(t, dir, "a.txt", "data A")
+	createTestFileAt(t, dir, "b.txt", "data B")
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlink tidak didukung: %v", err)
+	}
+
+	opts := hashutil.DirOptions{RelativePaths: true, Metadata: true}
+	results, err := hashutil.GenerateDirHashWithOptions(dir, "sha256,md5", opts, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	for _, r := range results {
+		if r.Meta == nil || r.Meta.Mode == "" || r.Meta.ModTime == "" {
+			t.Fatalf("metadata tidak tercatat: %+v", r)
+		}
+	}
+	if results[2].FilePath != "link" || results[2].Meta.LinkTarget != "a.txt" {
+		t.Errorf("target symlink tidak tercatat: %+v", results[2].Meta)
+	}
+
+	for _, format := range []string{"json", "csv", "txt"} {
+		path := filepath.Join(t.TempDir(), "meta."+format)
+		if err := output.SaveResultsToFile(results, path, format); err != nil {
+			t.Fatalf("gagal menyimpan %s: %v", format, err)
+		}
+		loaded, err := hashutil.LoadHashReference(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", format, err)
+		}
+		if report := hashutil.CompareResults(results, loaded); !report.OK() {
+			t.Errorf("format %s tidak round-trip: %+v", format, report)
+		}
+	}
+
+	// Perubahan mode saja harus dilaporkan terpisah dari perubahan isi
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatalf("Chmod gagal: %v", err)
+	}
+	report, err := hashutil.VerifyDirectory(dir, "sha256", results, hashutil.DirOptions{RelativePaths: true})
+	if err != nil {
+		t.Fatalf("VerifyDirectory gagal: %v", err)
+	}
+	if len(report.MetaChanged) != 1 || len(report.Mismatched) != 0 || report.OK() {
+		t.Fatalf("perubahan metadata seharusnya terdeteksi: %+v", report)
+	}
+	if !strings.HasPrefix(report.MetaChanged[0].Changes[0], "mode:") {
+		t.Errorf("perubahan mode tidak dilaporkan: %v", report.MetaChanged[0].Changes)
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	policy, err := hashutil.ParsePolicy(strings.NewReader(`
+# contoh kebijakan
+NORMAL = hash+size+perms+mtime
+etc/**        NORMAL
+etc/ssl/**    sha512+perms
+logs/*.log    growing
+!cache/**
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicy gagal: %v", err)
+	}
+	if r := policy.RuleFor("etc/ssl/key.pem"); r.Algorithm != "sha512" || !r.Hash || r.MTime {
+		t.Errorf("aturan terakhir yang cocok seharusnya menang: %+v", r)
+	}
+	if r := policy.RuleFor("readme"); !r.Hash || r.Size {
+		t.Errorf("DEFAULT seharusnya hanya hash: %+v", r)
+	}
+	if _, err := hashutil.ParsePolicy(strings.NewReader("etc/** bogus\n")); err == nil {
+		t.Error("atribut tidak dikenal seharusnya ditolak")
+	}
+
+	dir := t.TempDir()
+	for _, sub := range []string{"etc/ssl", "logs", "cache"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+	}
+	createTestFileAt(t, dir, "etc/ssl/key.pem", "key")
+	conf := createTestFileAt(t, dir, "etc/app.conf", "conf")
+	logFile := createTestFileAt(t, dir, "logs/app.log", "line 1\n")
+	createTestFileAt(t, dir, "cache/tmp", "tmp")
+
+	opts := hashutil.DirOptions{RelativePaths: true, Policy: policy}
+	baseline, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", opts, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	if len(baseline) != 3 {
+		t.Fatalf("path yang diabaikan seharusnya tidak di-hash: %+v", baseline)
+	}
+	for _, r := range baseline {
+		if r.Meta == nil {
+			t.Errorf("metadata seharusnya tercatat dengan policy: %+v", r)
+		}
+		if r.FilePath == "etc/ssl/key.pem" && r.HashType != "SHA512" {
+			t.Errorf("algoritma per-file dari policy tidak dipakai: %s", r.HashType)
+		}
+	}
+
+	// Log yang bertambah dan file cache baru bukan pelanggaran
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("gagal membuka log: %v", err)
+	}
+	f.WriteString("line 2\n")
+	f.Close()
+	createTestFileAt(t, dir, "cache/new", "new")
+
+	report, err := hashutil.VerifyDirectory(dir, "sha256", baseline, opts)
+	if err != nil {
+		t.Fatalf("VerifyDirectory gagal: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("perubahan yang diizinkan policy seharusnya lolos: %+v", report)
+	}
+
+	// Log yang menyusut dan perubahan mtime pada etc/ harus dilaporkan
+	os.WriteFile(logFile, []byte("x"), 0644)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(conf, later, later); err != nil {
+		t.Fatalf("Chtimes gagal: %v", err)
+	}
+	report, err = hashutil.VerifyDirectory(dir, "sha256", baseline, opts)
+	if err != nil {
+		t.Fatalf("VerifyDirectory gagal: %v", err)
+	}
+	if len(report.MetaChanged) != 2 || len(report.Mismatched) != 0 {
+		t.Errorf("log menyusut dan mtime seharusnya dilaporkan: %+v", report)
+	}
+}
+
+func TestSignedManifest(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "catmint.key")
+	pub, err := hashutil.GenerateKeyPair(keyPath, keyPath+".pub")
+	if err != nil {
+		t.Fatalf("GenerateKeyPair gagal: %v", err)
+	}
+	priv, err := hashutil.LoadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey gagal: %v", err)
+	}
+	if loaded, err := hashutil.LoadPublicKey(keyPath + ".pub"); err != nil || !loaded.Equal(pub) {
+		t.Fatalf("LoadPublicKey gagal: %v", err)
+	}
+
+	ref := filepath.Join(dir, "hash.json")
+	results := []hashutil.HashResult{{FilePath: "a.txt", HashType: "SHA256", Hash: "abc"}}
+	if err := output.SaveResultsToFile(results, ref, "json"); err != nil {
+		t.Fatalf("gagal menyimpan referensi: %v", err)
+	}
+	data, _ := os.ReadFile(ref)
+	os.WriteFile(ref+hashutil.SignatureExt, []byte(hashutil.FormatSignature(data, priv)), 0644)
+
+	loaded, err := hashutil.LoadSignedReference(ref, "", pub)
+	if err != nil || len(loaded) != 1 || loaded[0].Hash != "abc" {
+		t.Fatalf("referensi bertanda tangan seharusnya valid: %v %+v", err, loaded)
+	}
+
+	// Manifest yang diubah setelah ditandatangani harus ditolak
+	os.WriteFile(ref, []byte(strings.Replace(string(data), "abc", "def", 1)), 0644)
+	if _, err := hashutil.LoadSignedReference(ref, "", pub); !errors.Is(err, hashutil.ErrBadSignature) {
+		t.Errorf("seharusnya ErrBadSignature, didapat: %v", err)
+	}
+
+	// Envelope dengan tanda tangan tertanam
+	envelope, _ := json.Marshal(hashutil.NewSignedManifest(ref, data, priv))
+	signed := filepath.Join(dir, "hash.signed.json")
+	os.WriteFile(signed, envelope, 0644)
+	if loaded, err := hashutil.LoadSignedReference(signed, "", pub); err != nil || loaded[0].Hash != "abc" {
+		t.Errorf("envelope seharusnya valid: %v", err)
+	}
+	if _, err := hashutil.LoadHashReference(signed); !errors.Is(err, hashutil.ErrSignedManifest) {
+		t.Errorf("envelope tanpa public key seharusnya ditolak: %v", err)
+	}
+
+	other := filepath.Join(dir, "other.key")
+	otherPub, _ := hashutil.GenerateKeyPair(other, other+".pub")
+	if _, err := hashutil.LoadSignedReference(signed, "", otherPub); !errors.Is(err, hashutil.ErrBadSignature) {
+		t.Errorf("kunci lain seharusnya ditolak: %v", err)
+	}
+
+	// Nama manifest memilih parser, jadi ikut ditandatangani
+	tampered := hashutil.NewSignedManifest(ref, data, priv)
+	tampered.ManifestName = "hash.csv"
+	envelope, _ = json.Marshal(tampered)
+	os.WriteFile(signed, envelope, 0644)
+	if _, err := hashutil.LoadSignedReference(signed, "", pub); !errors.Is(err, hashutil.ErrBadSignature) {
+		t.Errorf("nama manifest yang diubah seharusnya ditolak: %v", err)
+	}
+	legacy := hashutil.NewSignedManifest(ref, data, priv)
+	legacy.Version = 1
+	envelope, _ = json.Marshal(legacy)
+	os.WriteFile(signed, envelope, 0644)
+	if _, err := hashutil.LoadSignedReference(signed, "", pub); err == nil {
+		t.Error("envelope versi 1 seharusnya ditolak")
+	}
+
+	// File checksum untuk verify -c juga diperiksa tanda tangannya
+	sums := filepath.Join(dir, "SHA256SUMS")
+	line := strings.Repeat("a", 64) + "  a.txt\n"
+	os.WriteFile(sums, []byte(line), 0644)
+	os.WriteFile(sums+hashutil.SignatureExt, []byte(hashutil.FormatSignature([]byte(line), priv)), 0644)
+	if entries, bad, err := hashutil.LoadSignedChecksumFile(sums, "", pub); err != nil || bad != 0 || len(entries) != 1 {
+		t.Errorf("file checksum bertanda tangan seharusnya valid: %v %d %+v", err, bad, entries)
+	}
+	os.WriteFile(sums, []byte(strings.Repeat("b", 64)+"  a.txt\n"), 0644)
+	if _, _, err := hashutil.LoadSignedChecksumFile(sums, "", pub); !errors.Is(err, hashutil.ErrBadSignature) {
+		t.Errorf("file checksum yang diubah seharusnya ditolak: %v", err)
+	}
+}
+
+func TestKeyedHashing(t *testing.T) {
+	file := createTestFile(t, "keyed.txt", "data")
+	ctx := context.Background()
+
+	if _, err := hashutil.GenerateFileHash(file, "hmac-sha256"); !errors.Is(err, hashutil.ErrKeyRequired) {
+		t.Fatalf("hmac tanpa kunci seharusnya ErrKeyRequired, didapat: %v", err)
+	}
+
+	result, err := hashutil.GenerateKeyedFileHash(ctx, file, "hmac-sha256", []byte("secret"))
+	if err != nil {
+		t.Fatalf("GenerateKeyedFileHash gagal: %v", err)
+	}
+	// echo -n data | openssl dgst -sha256 -hmac secret
+	expected := "1b2c16b75bd2a870c114153ccda5bcfca63314bc722fa160d690de133ccbb9db"
+	if result.HashType != "HMAC-SHA256" || result.Hash != expected {
+		t.Errorf("hasil HMAC salah: %s %s", result.HashType, result.Hash)
+	}
+	if err := hashutil.VerifyKeyedFileHash(ctx, file, "hmac-sha256", strings.ToUpper(expected), []byte("secret")); err != nil {
+		t.Errorf("VerifyKeyedFileHash seharusnya cocok: %v", err)
+	}
+
+	if err := hashutil.VerifyKeyedFileHash(ctx, file, "hmac-sha256", expected, []byte("other")); !errors.Is(err, hashutil.ErrHashMismatch) {
+		t.Errorf("kunci berbeda seharusnya tidak cocok: %v", err)
+	}
+
+	if _, err := hashutil.GenerateKeyedFileHash(ctx, file, "blake3-keyed", []byte("other")); err == nil {
+		t.Error("blake3-keyed seharusnya menolak kunci selain 32 byte")
+	}
+	if _, err := hashutil.GenerateKeyedFileHash(ctx, file, "blake3-keyed,hmac-blake3", []byte(strings.Repeat("k", 32))); err != nil {
+		t.Errorf("blake3-keyed dengan kunci 32 byte gagal: %v", err)
+	}
+
+	// Kunci eksplisit: dua pemanggil bisa memakai kunci berbeda
+	dir := filepath.Dir(file)
+	withKey := func(key string) string {
+		results, err := hashutil.GenerateDirHashWithOptions(dir, "hmac-sha256", hashutil.DirOptions{RelativePaths: true, Key: []byte(key)}, nil, nil)
+		if err != nil || len(results) == 0 {
+			t.Fatalf("GenerateDirHashWithOptions dengan Key gagal: %v", err)
+		}
+		digest, _ := results[0].Digest("hmac-sha256")
+		return digest
+	}
+	if withKey("secret") != expected || withKey("other") == expected {
+		t.Error("DirOptions.Key seharusnya dipakai untuk hmac")
+	}
+	if _, err := hashutil.GenerateDirHashWithOptions(dir, "hmac-sha256", hashutil.DirOptions{}, nil, nil); !errors.Is(err, hashutil.ErrKeyRequired) {
+		t.Errorf("tanpa kunci seharusnya ErrKeyRequired sebelum hashing: %v", err)
+	}
+	h, err := hashutil.GetKeyedHasher("hmac-sha256", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("data"))
+	if fmt.Sprintf("%x", h.Sum(nil)) != expected {
+		t.Error("GetKeyedHasher memberi hasil salah")
+	}
+}
+
+func TestMerkleTree(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	os.MkdirAll(filepath.Join(dir, "c"), 0755)
+	createTestFileAt(t, dir, "a/b/x", "x")
+	createTestFileAt(t, dir, "a/y", "y")
+	createTestFileAt(t, dir, "c/z", "z")
+
+	opts := hashutil.DirOptions{RelativePaths: true, Workers: 3}
+	results, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", opts, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	tree, err := hashutil.BuildMerkleTree(results, "sha256", hashutil.MerkleOptions{})
+	if err != nil {
+		t.Fatalf("BuildMerkleTree gagal: %v", err)
+	}
+
+	// Urutan input tidak boleh mempengaruhi root
+	reversed := []hashutil.HashResult{results[2], results[1], results[0]}
+	again, _ := hashutil.BuildMerkleTree(reversed, "sha256", hashutil.MerkleOptions{})
+	if again.Hash != tree.Hash {
+		t.Errorf("root tidak deterministik: %s != %s", again.Hash, tree.Hash)
+	}
+
+	os.WriteFile(filepath.Join(dir, "a", "b", "x"), []byte("changed"), 0644)
+	changed, _ := hashutil.GenerateDirHashWithOptions(dir, "sha256", opts, nil, nil)
+	changedTree, _ := hashutil.BuildMerkleTree(changed, "sha256", hashutil.MerkleOptions{})
+	if changedTree.Hash == tree.Hash {
+		t.Fatal("perubahan isi seharusnya mengubah root")
+	}
+	if tree.Find("c").Hash != changedTree.Find("c").Hash {
+		t.Error("subdirektori yang tidak berubah seharusnya memiliki hash yang sama")
+	}
+
+	var paths []string
+	for _, c := range hashutil.DiffMerkleTrees(tree, changedTree) {
+		paths = append(paths, c.Path)
+	}
+	if strings.Join(paths, ",") != ".,a,a/b,a/b/x" {
+		t.Errorf("subdirektori yang berbeda salah: %v", paths)
+	}
+
+	if _, err := hashutil.BuildMerkleTree([]hashutil.HashResult{{FilePath: "/abs", HashType: "SHA256", Hash: "00"}}, "sha256", hashutil.MerkleOptions{}); err == nil {
+		t.Error("path absolut seharusnya ditolak")
+	}
+}
+
+func TestMerkleInclusionProof(t *testing.T) {
+	results := []hashutil.HashResult{}
+	for _, name := range []string{"bin/tool", "bin/helper", "doc/readme", "LICENSE"} {
+		r, err := hashutil.GenerateFileHash(createTestFile(t, filepath.Base(name), "isi "+name), "sha256")
+		if err != nil {
+			t.Fatalf("GenerateFileHash gagal: %v", err)
+		}
+		r.FilePath = name
+		results = append(results, r)
+	}
+	tree, err := hashutil.BuildMerkleTree(results, "sha256", hashutil.MerkleOptions{})
+	if err != nil {
+		t.Fatalf("BuildMerkleTree gagal: %v", err)
+	}
+
+	proof, err := hashutil.BuildMerkleProof(results, "bin/tool", "sha256", hashutil.MerkleOptions{})
+	if err != nil {
+		t.Fatalf("BuildMerkleProof gagal: %v", err)
+	}
+	if proof.Root != tree.Hash || len(proof.Levels) != 2 {
+		t.Fatalf("proof tidak sesuai dengan tree: %+v", proof)
+	}
+	if err := hashutil.VerifyMerkleProof(proof, results[0].Hash, tree.Hash); err != nil {
+		t.Errorf("proof seharusnya valid: %v", err)
+	}
+
+	// Isi lain atau root lain harus ditolak
+	if err := hashutil.VerifyMerkleProof(proof, results[1].Hash, tree.Hash); !errors.Is(err, hashutil.ErrProofMismatch) {
+		t.Errorf("isi yang berbeda seharusnya ditolak: %v", err)
+	}
+	proof.Path = "bin/other"
+	if err := hashutil.VerifyMerkleProof(proof, results[0].Hash, tree.Hash); !errors.Is(err, hashutil.ErrProofMismatch) {
+		t.Errorf("path yang berbeda seharusnya ditolak: %v", err)
+	}
+	if _, err := hashutil.BuildMerkleProof(results, "bin", "sha256", hashutil.MerkleOptions{}); err == nil {
+		t.Error("proof untuk direktori seharusnya ditolak")
+	}
+
+	// Tree dengan algoritma berkunci: kunci diberikan secara eksplisit
+	key := []byte("secret")
+	keyed := make([]hashutil.HashResult, len(results))
+	for i, name := range []string{"bin/tool", "bin/helper", "doc/readme", "LICENSE"} {
+		r, err := hashutil.GenerateKeyedFileHash(context.Background(), createTestFile(t, filepath.Base(name), "isi "+name), "hmac-sha256", key)
+		if err != nil {
+			t.Fatalf("GenerateKeyedFileHash gagal: %v", err)
+		}
+		r.FilePath = name
+		keyed[i] = r
+	}
+	keyedProof, err := hashutil.BuildMerkleProof(keyed, "bin/tool", "hmac-sha256", hashutil.MerkleOptions{Key: key})
+	if err != nil {
+		t.Fatalf("BuildMerkleProof berkunci gagal: %v", err)
+	}
+	if err := hashutil.VerifyKeyedMerkleProof(keyedProof, keyed[0].Hash, keyedProof.Root, key); err != nil {
+		t.Errorf("proof berkunci seharusnya valid: %v", err)
+	}
+	if err := hashutil.VerifyMerkleProof(keyedProof, keyed[0].Hash, keyedProof.Root); !errors.Is(err, hashutil.ErrKeyRequired) {
+		t.Errorf("proof berkunci tanpa kunci seharusnya ErrKeyRequired: %v", err)
+	}
+}
+
+func TestStreamingResultFile(t *testing.T) {
+	results := []hashutil.HashResult{
+		{FilePath: "a.txt", HashType: "SHA256", Hash: "aa"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "bb", Digests: []hashutil.Digest{{HashType: "SHA256", Hash: "bb"}, {HashType: "MD5", Hash: "cc"}}},
+	}
+
+	for _, format := range []string{"json", "csv", "txt", "ndjson"} {
+		path := filepath.Join(t.TempDir(), "hash."+format)
+		w, err := output.CreateResultFile(path, format, output.Options{})
+		if err != nil {
+			t.Fatalf("CreateResultFile %s gagal: %v", format, err)
+		}
+		for _, r := range results {
+			if err := w.Write(r); err != nil {
+				t.Fatalf("Write %s gagal: %v", format, err)
+			}
+		}
+		// Sebelum Close target belum ada, hasil ditulis ke file sementara
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: target seharusnya belum dibuat sebelum Close", format)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close %s gagal: %v", format, err)
+		}
+		if format == "ndjson" {
+			data, _ := os.ReadFile(path)
+			if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+				t.Errorf("ndjson seharusnya satu objek per baris: %q", data)
+			}
+			continue
+		}
+		loaded, err := hashutil.LoadHashReference(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", format, err)
+		}
+		if report := hashutil.CompareResults(results, loaded); !report.OK() {
+			t.Errorf("format %s tidak round-trip: %+v", format, report)
+		}
+	}
+
+	// Abort tidak boleh meninggalkan file apa pun
+	dir := t.TempDir()
+	w, err := output.CreateResultFile(filepath.Join(dir, "hash.json"), "json", output.Options{})
+	if err != nil {
+		t.Fatalf("CreateResultFile gagal: %v", err)
+	}
+	w.Write(results[0])
+	w.Abort()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Abort seharusnya menghapus file sementara: %v", entries)
+	}
+
+	// Hasil parsial tidak menimpa manifest lengkap yang sudah ada
+	baseline := filepath.Join(dir, "base.json")
+	os.WriteFile(baseline, []byte("[]"), 0644)
+	w, err = output.CreateResultFile(baseline, "json", output.Options{})
+	if err != nil {
+		t.Fatalf("CreateResultFile gagal: %v", err)
+	}
+	w.Write(results[0])
+	if err := w.ClosePartial(); err != nil {
+		t.Fatalf("ClosePartial gagal: %v", err)
+	}
+	if data, _ := os.ReadFile(baseline); string(data) != "[]" {
+		t.Errorf("baseline seharusnya tidak berubah: %s", data)
+	}
+	if partial, err := hashutil.LoadHashReference(filepath.Join(dir, "base.partial.json")); err != nil || len(partial) != 1 {
+		t.Errorf("hasil parsial seharusnya di base.partial.json: %v %+v", err, partial)
+	}
+	os.Remove(baseline)
+	os.Remove(filepath.Join(dir, "base.partial.json"))
+
+	// Output di dalam direktori yang di-hash tidak ikut tercatat
+	createTestFileAt(t, dir, "data.txt", "data")
+	out := filepath.Join(dir, "hash.json")
+	w, err = output.CreateResultFile(out, "json", output.Options{})
+	if err != nil {
+		t.Fatalf("CreateResultFile gagal: %v", err)
+	}
+	defer w.Abort()
+	os.WriteFile(out, []byte("[]"), 0644)
+	own, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{RelativePaths: true, OwnFiles: []string{out}}, nil, nil)
+	if err != nil || len(own) != 1 || own[0].FilePath != "data.txt" {
+		t.Errorf("file output dan file sementaranya seharusnya dilewati: %v %+v", err, own)
+	}
+}
+
+func TestNDJSONAndTSVReferences(t *testing.T) {
+	results := []hashutil.HashResult{
+		{FilePath: "a b.txt", HashType: "SHA256", Hash: "aa"},
+		{FilePath: "c.txt", HashType: "MD5", Hash: "cc"},
+	}
+	for _, name := range []string{"hash.ndjson", "hash.jsonl", "hash.tsv"} {
+		format := strings.TrimPrefix(filepath.Ext(name), ".")
+		if format == "jsonl" {
+			format = "ndjson"
+		}
+		path := filepath.Join(t.TempDir(), name)
+		if err := output.SaveResultsToFile(results, path, format); err != nil {
+			t.Fatalf("gagal menyimpan %s: %v", name, err)
+		}
+		loaded, err := hashutil.LoadHashReference(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", name, err)
+		}
+		if report := hashutil.CompareResults(results, loaded); !report.OK() {
+			t.Errorf("%s tidak round-trip: %+v", name, report)
+		}
+	}
+
+	var buf strings.Builder
+	w, err := output.NewResultWriter(&buf, "tsv", output.Options{})
+	if err != nil {
+		t.Fatalf("NewResultWriter gagal: %v", err)
+	}
+	w.Write(results[0])
+	w.Close()
+	if !strings.Contains(buf.String(), "a b.txt\tSHA256\taa\n") {
+		t.Errorf("baris tsv salah: %q", buf.String())
+	}
+	if _, err := output.NewResultWriter(&buf, "xml", output.Options{}); err == nil {
+		t.Error("format tidak dikenal seharusnya ditolak")
+	}
+}
+
+func TestResumableVerify(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 6; i++ {
+		createTestFileAt(t, dir, fmt.Sprintf("f%d", i), fmt.Sprintf("isi %d", i))
+	}
+	opts := hashutil.DirOptions{RelativePaths: true}
+	reference, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", opts, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateDirHashWithOptions gagal: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "f1"), []byte("berubah"), 0644)
+	os.Remove(filepath.Join(dir, "f4"))
+	createTestFileAt(t, dir, "baru", "baru")
+
+	full, err := hashutil.VerifyDirectory(dir, "sha256", reference, opts)
+	if err != nil {
+		t.Fatalf("VerifyDirectory gagal: %v", err)
+	}
+
+	// Simulasikan run yang terhenti: checkpoint hanya berisi sebagian file
+	path := filepath.Join(t.TempDir(), "verify.ckpt")
+	partial, _ := hashutil.OpenCheckpoint(path, false)
+	partialOpts := opts
+	partialOpts.Checkpoint = partial
+	partialOpts.Filter = &hashutil.Filter{Include: []string{"f0", "f1"}}
+	if _, err := hashutil.VerifyDirectory(dir, "sha256", reference, partialOpts); err != nil {
+		t.Fatalf("VerifyDirectory parsial gagal: %v", err)
+	}
+	// Filter berbeda = verifikasi berbeda, jadi pakai checkpoint dengan sidik jari baru
+	data, _ := os.ReadFile(path)
+	var state map[string]any
+	json.Unmarshal(data, &state)
+	state["fingerprint"] = ""
+	data, _ = json.Marshal(state)
+	os.WriteFile(path, data, 0644)
+
+	resumed, err := hashutil.OpenCheckpoint(path, true)
+	if err != nil || resumed.Len() != 2 {
+		t.Fatalf("checkpoint seharusnya berisi 2 file: %v %d", err, resumed.Len())
+	}
+	resumeOpts := opts
+	resumeOpts.Checkpoint = resumed
+	report, err := hashutil.VerifyDirectory(dir, "sha256", reference, resumeOpts)
+	if err != nil {
+		t.Fatalf("VerifyDirectory resume gagal: %v", err)
+	}
+	a, _ := json.Marshal(full)
+	b, _ := json.Marshal(report)
+	if string(a) != string(b) {
+		t.Errorf("laporan resume seharusnya identik:\n%s\n%s", a, b)
+	}
+
+	// Checkpoint dari verifikasi lain harus ditolak
+	other, _ := hashutil.OpenCheckpoint(path, true)
+	otherOpts := opts
+	otherOpts.Checkpoint = other
+	if _, err := hashutil.VerifyDirectory(dir, "sha256", reference[:1], otherOpts); !errors.Is(err, hashutil.ErrCheckpointMismatch) {
+		t.Errorf("seharusnya ErrCheckpointMismatch, didapat: %v", err)
+	}
+
+	// Kunci HMAC dan policy ikut menentukan hasil, jadi checkpoint dengan
+	// kunci atau policy lain juga harus ditolak
+	keyedOpts := hashutil.DirOptions{RelativePaths: true, Key: []byte("kunci A")}
+	keyedRef, _ := hashutil.GenerateDirHashWithOptions(dir, "hmac-sha256", keyedOpts, nil, nil)
+	keyedOpts.Policy, _ = hashutil.ParsePolicy(strings.NewReader("f0 hash+size\n"))
+	keyedPath := filepath.Join(t.TempDir(), "keyed.ckpt")
+	keyedOpts.Checkpoint, _ = hashutil.OpenCheckpoint(keyedPath, false)
+	if _, err := hashutil.VerifyDirectory(dir, "hmac-sha256", keyedRef, keyedOpts); err != nil {
+		t.Fatalf("VerifyDirectory dengan kunci gagal: %v", err)
+	}
+	keyedOpts.Key = []byte("kunci B")
+	keyedOpts.Checkpoint, _ = hashutil.OpenCheckpoint(keyedPath, true)
+	if _, err := hashutil.VerifyDirectory(dir, "hmac-sha256", keyedRef, keyedOpts); !errors.Is(err, hashutil.ErrCheckpointMismatch) {
+		t.Errorf("kunci berbeda seharusnya ErrCheckpointMismatch, didapat: %v", err)
+	}
+	keyedOpts.Key = []byte("kunci A")
+	keyedOpts.Policy, _ = hashutil.ParsePolicy(strings.NewReader("f0 size\n"))
+	keyedOpts.Checkpoint, _ = hashutil.OpenCheckpoint(keyedPath, true)
+	if _, err := hashutil.VerifyDirectory(dir, "hmac-sha256", keyedRef, keyedOpts); !errors.Is(err, hashutil.ErrCheckpointMismatch) {
+		t.Errorf("policy berbeda seharusnya ErrCheckpointMismatch, didapat: %v", err)
+	}
+
+	// Checkpoint di dalam direktori yang diverifikasi bukan file tambahan
+	inTree, _ := hashutil.OpenCheckpoint(filepath.Join(dir, "ref.json.checkpoint"), false)
+	inTree.Save()
+	createTestFileAt(t, dir, ".ref.json.checkpoint.tmp-123", "sisa")
+	inTreeOpts := opts
+	inTreeOpts.Checkpoint = inTree
+	inTreeReport, err := hashutil.VerifyDirectory(dir, "sha256", reference, inTreeOpts)
+	if err != nil || len(inTreeReport.Extra) != len(full.Extra) {
+		t.Errorf("checkpoint dan file sementaranya seharusnya dilewati: %v %+v", err, inTreeReport.Extra)
+	}
+}
+
+func TestManifestHeader(t *testing.T) {
+	tmp := t.TempDir()
+	header := &hashutil.ManifestHeader{
+		Tool:       "catmint v1.1.1",
+		Created:    "2026-10-17T10:00:00Z",
+		Host:       "build01",
+		Root:       "/srv/data",
+		Algorithms: "sha256",
+		Options:    map[string]string{"relative": "true", "exclude": "*.tmp"},
+	}
+	manifest := hashutil.Manifest{Header: header, Entries: dummyResults}
+
+	for _, name := range []string{"m.json", "m.ndjson", "m.csv", "m.tsv", "m.txt"} {
+		path := filepath.Join(tmp, name)
+		format := strings.TrimPrefix(filepath.Ext(name), ".")
+		if err := output.SaveManifestToFile(manifest, path, format); err != nil {
+			t.Fatalf("gagal menyimpan %s: %v", name, err)
+		}
+		loaded, err := hashutil.LoadManifest(path)
+		if err != nil {
+			t.Fatalf("gagal memuat %s: %v", name, err)
+		}
+		if loaded.Version != hashutil.ManifestVersion || loaded.Header == nil {
+			t.Fatalf("%s: header tidak terbaca: %+v", name, loaded)
+		}
+		if loaded.Header.Root != header.Root || loaded.Header.Tool != header.Tool ||
+			loaded.Header.Host != header.Host || loaded.Header.Options["exclude"] != "*.tmp" {
+			t.Errorf("%s: header tidak sesuai: %+v", name, loaded.Header)
+		}
+		entries, err := hashutil.LoadHashReference(path)
+		if err != nil || len(entries) != len(dummyResults) || entries[1].Hash != "def456" {
+			t.Errorf("%s: entri tidak sesuai: %v %+v", name, err, entries)
+		}
+	}
+
+	// File lama tanpa header tetap terbaca, dengan Header nil
+	legacy := filepath.Join(tmp, "legacy.json")
+	if err := output.SaveResultsToFile(dummyResults, legacy, "json"); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := hashutil.LoadManifest(legacy)
+	if err != nil || loaded.Header != nil || len(loaded.Entries) != len(dummyResults) {
+		t.Errorf("manifest lama tidak terbaca dengan benar: %v %+v", err, loaded)
+	}
+
+	// Versi dokumen yang lebih baru ditolak
+	future := filepath.Join(tmp, "future.json")
+	os.WriteFile(future, []byte(`{"manifest_version": 99, "entries": []}`), 0644)
+	if _, err := hashutil.LoadManifest(future); err == nil {
+		t.Error("manifest_version yang tidak dikenal seharusnya ditolak")
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	old := []hashutil.HashResult{
+		{FilePath: "a.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "bbb"},
+		{FilePath: "dir/c.txt", HashType: "SHA256", Hash: "ccc"},
+		{FilePath: "gone.txt", HashType: "SHA256", Hash: "ggg"},
+	}
+	new := []hashutil.HashResult{
+		{FilePath: "./a.txt", HashType: "sha256", Hash: "AAA"},
+		{FilePath: "b.txt", HashType: "SHA256", Hash: "b22"},
+		{FilePath: "moved/c.txt", HashType: "SHA256", Hash: "ccc"},
+		{FilePath: "new.txt", HashType: "SHA256", Hash: "nnn"},
+	}
+
+	diff := hashutil.DiffManifests(old, new)
+	if diff.Empty() || diff.Unchanged != 1 {
+		t.Fatalf("diff tidak sesuai: %+v", diff)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].Path != "b.txt" {
+		t.Errorf("modified seharusnya b.txt: %+v", diff.Modified)
+	}
+	if len(diff.Renamed) != 1 || diff.Renamed[0].OldPath != "dir/c.txt" || diff.Renamed[0].Path != "moved/c.txt" {
+		t.Errorf("rename dir/c.txt -> moved/c.txt tidak terdeteksi: %+v", diff.Renamed)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "gone.txt" {
+		t.Errorf("removed seharusnya gone.txt: %+v", diff.Removed)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Path != "new.txt" {
+		t.Errorf("added seharusnya new.txt: %+v", diff.Added)
+	}
+
+	if d := hashutil.DiffManifests(old, old); !d.Empty() || d.Unchanged != len(old) {
+		t.Errorf("manifest yang sama seharusnya tidak berbeda: %+v", d)
+	}
+
+	// Root yang diberikan relatif maupun absolut dilepas dari path entri
+	relHeader := []byte("# catmint-manifest: 1\n# root: /srv/data\n# walk_root: data\nFile Path,Hash Type,Hash\ndata/a.txt,SHA256,aa\n")
+	m, err := hashutil.ParseManifest(relHeader, "old.csv")
+	if err != nil || m.Header.EntryRoot() != "data" {
+		t.Fatalf("walk_root seharusnya terbaca: %v %+v", err, m.Header)
+	}
+	absHeader := &hashutil.ManifestHeader{Root: "/srv/data"}
+	relOpt := &hashutil.ManifestHeader{Root: "/srv/data", Options: map[string]string{"relative": "true"}}
+	if absHeader.EntryRoot() != "/srv/data" || relOpt.EntryRoot() != "" {
+		t.Errorf("EntryRoot tidak sesuai: %q %q", absHeader.EntryRoot(), relOpt.EntryRoot())
+	}
+	a := hashutil.RebaseResults(m.Entries, m.Header.EntryRoot())
+	b := hashutil.RebaseResults([]hashutil.HashResult{{FilePath: "/srv/data/a.txt", HashType: "SHA256", Hash: "aa"}}, absHeader.EntryRoot())
+	if d := hashutil.DiffManifests(a, b); !d.Empty() {
+		t.Errorf("root relatif dan absolut seharusnya sama: %+v", d)
+	}
+}
+
+func TestCompareDirectories(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	for _, dir := range []string{dirA, dirB} {
+		os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+		createTestFileAt(t, dir, filepath.Join("sub", "same.txt"), "sama")
+	}
+	createTestFileAt(t, dirA, "beda.txt", "isi A")
+	createTestFileAt(t, dirB, "beda.txt", "isi B")
+	createTestFileAt(t, dirA, "ukuran.txt", "panjang sekali")
+	createTestFileAt(t, dirB, "ukuran.txt", "pendek")
+	createTestFileAt(t, dirA, "hanya-a.txt", "a")
+	createTestFileAt(t, dirB, "hanya-b.txt", "b")
+
+	for _, fast := range []bool{false, true} {
+		report, err := hashutil.CompareDirectories(dirA, dirB, "sha256", hashutil.CompareOptions{Fast: fast})
+		if err != nil {
+			t.Fatalf("CompareDirectories (fast=%v) gagal: %v", fast, err)
+		}
+		if report.OK() {
+			t.Errorf("fast=%v: direktori berbeda seharusnya tidak OK", fast)
+		}
+		if len(report.Identical) != 1 || report.Identical[0].Path != "sub/same.txt" {
+			t.Errorf("fast=%v: identical tidak sesuai: %+v", fast, report.Identical)
+		}
+		if len(report.Differing) != 2 || report.Differing[0].Path != "beda.txt" || report.Differing[1].Path != "ukuran.txt" {
+			t.Errorf("fast=%v: differing tidak sesuai: %+v", fast, report.Differing)
+		}
+		if fast && report.Differing[1].HashA != "" {
+			t.Errorf("mode fast seharusnya tidak menghash file dengan ukuran berbeda: %+v", report.Differing[1])
+		}
+		if len(report.OnlyInA) != 1 || report.OnlyInA[0].Path != "hanya-a.txt" ||
+			len(report.OnlyInB) != 1 || report.OnlyInB[0].Path != "hanya-b.txt" {
+			t.Errorf("fast=%v: only-in tidak sesuai: %+v %+v", fast, report.OnlyInA, report.OnlyInB)
+		}
+	}
+
+	report, err := hashutil.CompareDirectories(dirA, dirA, "sha256", hashutil.CompareOptions{})
+	if err != nil || !report.OK() {
+		t.Errorf("direktori yang sama seharusnya OK: %v %+v", err, report)
+	}
+}
+
+func TestRenameAndCopyDetection(t *testing.T) {
+	reference := []hashutil.HashResult{
+		{FilePath: "docs/readme.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "keep.txt", HashType: "SHA256", Hash: "kkk"},
+		{FilePath: "gone.txt", HashType: "SHA256", Hash: "ggg"},
+	}
+	actual := []hashutil.HashResult{
+		{FilePath: "archive/readme.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "backup/readme-copy.txt", HashType: "SHA256", Hash: "aaa"},
+		{FilePath: "keep.txt", HashType: "SHA256", Hash: "kkk"},
+		{FilePath: "keep-copy.txt", HashType: "sha256", Hash: "KKK"},
+		{FilePath: "new.txt", HashType: "SHA256", Hash: "nnn"},
+	}
+
+	report := hashutil.CompareResults(actual, reference)
+	if len(report.Renamed) != 1 || report.Renamed[0].From != "docs/readme.txt" || report.Renamed[0].FilePath != "archive/readme.txt" {
+		t.Errorf("rename docs/readme.txt -> archive/readme.txt tidak terdeteksi: %+v", report.Renamed)
+	}
+	if len(report.Copied) != 2 || report.Copied[0].FilePath != "backup/readme-copy.txt" || report.Copied[0].From != "docs/readme.txt" ||
+		report.Copied[1].FilePath != "keep-copy.txt" || report.Copied[1].From != "keep.txt" {
+		t.Errorf("copy tidak sesuai: %+v", report.Copied)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].FilePath != "gone.txt" {
+		t.Errorf("hanya gone.txt yang seharusnya hilang: %+v", report.Missing)
+	}
+	if len(report.Extra) != 1 || report.Extra[0].FilePath != "new.txt" {
+		t.Errorf("hanya new.txt yang seharusnya baru: %+v", report.Extra)
+	}
+	if report.OK() {
+		t.Error("laporan dengan rename seharusnya tidak OK")
+	}
+
+	// File asal yang ternyata masih ada (tidak terbaca) berarti salinan, bukan rename
+	report.MarkUnreadable("docs/readme.txt", os.ErrPermission)
+	if len(report.Renamed) != 0 || len(report.Copied) != 3 {
+		t.Errorf("rename seharusnya menjadi copy: %+v %+v", report.Renamed, report.Copied)
+	}
+
+	// Hal yang sama lewat verify -d pada disk
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	createTestFileAt(t, dir, filepath.Join("docs", "readme.txt"), "baca saya")
+	ref, err := hashutil.GenerateDirHashWithOptions(dir, "sha256", hashutil.DirOptions{RelativePaths: true}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(dir, "archive"), 0755)
+	os.Rename(filepath.Join(dir, "docs", "readme.txt"), filepath.Join(dir, "archive", "readme.txt"))
+	verify, err := hashutil.VerifyDirectory(dir, "sha256", ref, hashutil.DirOptions{RelativePaths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verify.Renamed) != 1 || len(verify.Missing) != 0 || len(verify.Extra) != 0 {
+		t.Errorf("file yang dipindah seharusnya dilaporkan sebagai rename: %+v", verify)
+	}
+
+	// Referensi dengan algoritma campuran: digest sekunder file baru juga dicocokkan
+	createTestFileAt(t, dir, "lain.txt", "isi lain")
+	sha512Ref, err := hashutil.GenerateDirHashWithOptions(dir, "sha512", hashutil.DirOptions{RelativePaths: true}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := []hashutil.HashResult{{FilePath: "archive/readme.txt", HashType: ref[0].HashType, Hash: ref[0].Hash}}
+	for _, r := range sha512Ref {
+		if r.FilePath == "lain.txt" {
+			mixed = append(mixed, r)
+		}
+	}
+	os.Rename(filepath.Join(dir, "lain.txt"), filepath.Join(dir, "baru.txt"))
+	verify, err = hashutil.VerifyDirectory(dir, "sha256", mixed, hashutil.DirOptions{RelativePaths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verify.Renamed) != 1 || verify.Renamed[0].From != "lain.txt" || len(verify.Extra) != 0 {
+		t.Errorf("rename dengan algoritma campuran seharusnya terdeteksi: %+v", verify)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "b"), 0755)
+	// Isi sama dengan awal yang sama melewati batas partial hash
+	same := strings.Repeat("x", 100) + "sama"
+	createTestFileAt(t, dir, filepath.Join("a", "satu.txt"), same)
+	createTestFileAt(t, dir, filepath.Join("b", "dua.txt"), same)
+	createTestFileAt(t, dir, filepath.Join("b", "tiga.txt"), same)
+	// Ukuran dan awal sama, isi berbeda
+	createTestFileAt(t, dir, filepath.Join("a", "mirip.txt"), strings.Repeat("x", 100)+"beda")
+	createTestFileAt(t, dir, "unik.txt", "unik")
+	createTestFileAt(t, dir, "kosong1.txt", "")
+	createTestFileAt(t, dir, "kosong2.txt", "")
+
+	opts := hashutil.DupesOptions{PartialSize: 16}
+	report, err := hashutil.FindDuplicates([]string{dir}, "sha256", opts, nil)
+	if err != nil {
+		t.Fatalf("FindDuplicates gagal: %v", err)
+	}
+	if len(report.Groups) != 1 || len(report.Groups[0].Files) != 3 {
+		t.Fatalf("seharusnya satu grup berisi 3 file: %+v", report.Groups)
+	}
+	size := int64(len(same))
+	if report.Groups[0].Size != size || report.Reclaimable != 2*size {
+		t.Errorf("reclaimable tidak sesuai: %+v", report)
+	}
+	if report.Groups[0].Files[0] != filepath.Join(dir, "a", "satu.txt") {
+		t.Errorf("file pertama seharusnya a/satu.txt: %v", report.Groups[0].Files)
+	}
+
+	opts.Empty = true
+	if withEmpty, _ := hashutil.FindDuplicates([]string{dir}, "sha256", opts, nil); len(withEmpty.Groups) != 2 {
+		t.Errorf("file kosong seharusnya dikelompokkan dengan Empty: %+v", withEmpty.Groups)
+	}
+
+	linked := hashutil.LinkDuplicates(report.Groups[0], func(path string, err error) {
+		t.Errorf("gagal membuat hard link %s: %v", path, err)
+	})
+	if len(linked) != 2 {
+		t.Fatalf("seharusnya 2 file diganti hard link: %v", linked)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "b", "tiga.txt"))
+	if string(data) != same {
+		t.Error("isi file setelah hard link berubah")
+	}
+	// Hard link yang sudah ada tidak dihitung lagi
+	after, err := hashutil.FindDuplicates([]string{dir}, "sha256", hashutil.DupesOptions{PartialSize: 16}, nil)
+	if err != nil || len(after.Groups) != 0 || after.Reclaimable != 0 {
+		t.Errorf("setelah hard link tidak ada ruang yang bisa diambil lagi: %v %+v", err, after)
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"catmint/hashutil"
+	"catmint/internal"
+)
+
+// cacheFlags holds the incremental hashing options shared by hash and verify.
+type cacheFlags struct {
+	path     string
+	rehash   bool
+	paranoid bool
+}
+
+func addCacheFlags(fs *flag.FlagSet) *cacheFlags {
+	c := &cacheFlags{}
+	fs.StringVar(&c.path, "cache", "", "Directory mode: cache file used to skip files whose size, mtime and inode are unchanged")
+	fs.BoolVar(&c.rehash, "rehash", false, "With -cache: ignore cached digests and rehash everything (the cache is refreshed)")
+	fs.BoolVar(&c.paranoid, "paranoid", false, "With -cache: rehash everything and flag files whose content changed without a metadata change")
+	return c
+}
+
+// open loads the cache and applies it to opts, leaving the cache file itself
+// out of the walk. It returns nil when no cache was requested; on error the
+// exit code to use is returned as well.
+func (c *cacheFlags) open(opts *hashutil.DirOptions) (*hashutil.Cache, int, error) {
+	if strings.TrimSpace(c.path) == "" {
+		if c.rehash || c.paranoid {
+			return nil, exitUsage, fmt.Errorf("-rehash and -paranoid require -cache")
+		}
+		return nil, exitOK, nil
+	}
+	cache, err := hashutil.OpenCache(c.path)
+	if err != nil {
+		return nil, referenceExitCode(err), err
+	}
+	opts.Cache = cache
+	opts.Rehash = c.rehash
+	opts.Paranoid = c.paranoid
+	opts.OwnFiles = append(opts.OwnFiles, c.path)
+	return cache, exitOK, nil
+}
+
+// reportCacheMismatch prints a paranoid-mode cache warning and reports whether err was one.
+func reportCacheMismatch(path string, err error) bool {
+	var mismatch *hashutil.CacheMismatchError
+	if !errors.As(err, &mismatch) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "WARNING: %s: %v\n", path, mismatch)
+	return true
+}
+
+// saveCache writes the cache back and reports how much work it saved.
+func saveCache(cache *hashutil.Cache) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save cache %s: %v\n", cache.Path(), err)
+		return
+	}
+	stats := cache.Stats()
+	fmt.Fprintf(os.Stderr, "Cache: %d hit(s), %d miss(es), %d entries in %s\n", stats.Hits, stats.Misses, stats.Entries, cache.Path())
+}
+
+func runCache(args []string) {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		cachePath string
+		list      bool
+		prune     bool
+	)
+
+	fs.StringVar(&cachePath, "cache", "", "Path of the cache file created with 'hash -cache' or 'verify -cache'")
+	fs.BoolVar(&list, "list", false, "List every cached entry")
+	fs.BoolVar(&prune, "prune", false, "Remove entries whose file is gone or whose size, mtime or inode changed")
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("cache", fs, version, `
+Without -list or -prune, a short summary of the cache is printed.
+
+Examples:
+  catmint cache -cache .catmint-cache.json
+  catmint cache -cache .catmint-cache.json -list
+  catmint cache -cache .catmint-cache.json -prune
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint cache --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if strings.TrimSpace(cachePath) == "" {
+		fmt.Fprintln(os.Stderr, "Error: please provide -cache")
+		fmt.Fprintln(os.Stderr, "Run 'catmint cache --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+
+	cache, err := hashutil.OpenCache(cachePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitParse)
+	}
+
+	if prune {
+		removed := cache.Prune()
+		if err := cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		}
+		fmt.Printf("Pruned %d stale entries, %d remaining\n", removed, cache.Stats().Entries)
+	}
+
+	if list {
+		for _, e := range cache.Entries() {
+			fmt.Printf("%s %s %s size=%d mtime=%s\n", e.HashType, e.Hash, e.Path, e.Size,
+				time.Unix(0, e.ModTime).UTC().Format(time.RFC3339))
+		}
+	}
+
+	if !list && !prune {
+		entries := cache.Entries()
+		files := make(map[string]bool)
+		algs := make(map[string]int)
+		stale := 0
+		for _, e := range entries {
+			files[e.Path] = true
+			algs[e.HashType]++
+			if !e.Fresh() {
+				stale++
+			}
+		}
+		fmt.Printf("Cache file : %s\n", cachePath)
+		fmt.Printf("Entries    : %d (%d file(s))\n", len(entries), len(files))
+		names := make([]string, 0, len(algs))
+		for alg := range algs {
+			names = append(names, alg)
+		}
+		sort.Strings(names)
+		for _, alg := range names {
+			fmt.Printf("  %-9s: %d\n", alg, algs[alg])
+		}
+		fmt.Printf("Stale      : %d (remove with -prune)\n", stale)
+	}
+}
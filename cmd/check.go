@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"catmint/hashutil"
+)
+
+type checkOptions struct {
+	// alg is used for lines that do not name an algorithm and whose digest
+	// length is ambiguous.
+	alg           string
+	quiet         bool
+	status        bool
+	ignoreMissing bool
+	// key is the key of the keyed algorithms.
+	key []byte
+	// pubKeyPath and sigPath require a valid signature, as for -ref.
+	pubKeyPath string
+	sigPath    string
+}
+
+// runCheck verifies every entry of a checksum file like `sha256sum -c` and
+// returns the exit code. An error is only returned when the checksum file
+// itself cannot be loaded or the run was cancelled.
+func runCheck(ctx context.Context, checkFile string, opts checkOptions) (int, error) {
+	entries, bad, err := loadChecksumFile(checkFile, opts.sigPath, opts.pubKeyPath)
+	if err != nil {
+		return referenceExitCode(err), err
+	}
+	if len(entries) == 0 {
+		return exitParse, fmt.Errorf("%s: no properly formatted checksum lines found", checkFile)
+	}
+
+	failed, unreadable, verified := 0, 0, 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return exitInterrupted, ctx.Err()
+		}
+
+		alg := strings.TrimSpace(entry.HashType)
+		if alg == "" {
+			alg = opts.alg
+		}
+
+		result, err := hashutil.GenerateKeyedFileHash(ctx, entry.FilePath, alg, opts.key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return exitInterrupted, ctx.Err()
+			}
+			if opts.ignoreMissing && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			unreadable++
+			if !opts.status {
+				fmt.Fprintf(os.Stderr, "catmint: %s: %v\n", entry.FilePath, err)
+				fmt.Printf("%s: FAILED open or read\n", entry.FilePath)
+			}
+			continue
+		}
+
+		verified++
+		if hashutil.HashEqual(result.Hash, entry.Hash) {
+			if !opts.quiet && !opts.status {
+				fmt.Printf("%s: OK\n", entry.FilePath)
+			}
+			continue
+		}
+		failed++
+		if !opts.status {
+			fmt.Printf("%s: FAILED\n", entry.FilePath)
+		}
+	}
+
+	if !opts.status {
+		if bad > 0 {
+			fmt.Fprintf(os.Stderr, "catmint: WARNING: %d line(s) improperly formatted\n", bad)
+		}
+		if unreadable > 0 {
+			fmt.Fprintf(os.Stderr, "catmint: WARNING: %d listed file(s) could not be read\n", unreadable)
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "catmint: WARNING: %d computed checksum(s) did NOT match\n", failed)
+		}
+	}
+	if opts.ignoreMissing && verified == 0 && unreadable == 0 {
+		if !opts.status {
+			fmt.Fprintf(os.Stderr, "catmint: %s: no file was verified\n", checkFile)
+		}
+		return exitIO, nil
+	}
+	switch {
+	case failed > 0:
+		return exitIntegrity, nil
+	case unreadable > 0:
+		return exitIO, nil
+	default:
+		return exitOK, nil
+	}
+}
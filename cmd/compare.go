@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"catmint/hashutil"
+	"catmint/internal"
+)
+
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		alg          string
+		fast         bool
+		jobs         int
+		reportFormat string
+	)
+
+	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm: sha256, sha512, sha1, md5, sha3-256, blake3, hmac-<alg>, blake3-keyed")
+	fs.StringVar(&alg, "a", "sha256", "Alias for -alg")
+	fs.BoolVar(&fast, "fast", false, "Only hash files present in both trees with the same size; a size difference is reported without hashing")
+
+	keys := addKeyFlags(fs)
+	filters := addFilterFlags(fs)
+
+	fs.IntVar(&jobs, "jobs", 0, "Number of files hashed in parallel per tree (default: number of CPUs)")
+	fs.IntVar(&jobs, "j", 0, "Alias for -jobs")
+
+	fs.StringVar(&reportFormat, "report", "text", "Report format: text, json")
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("compare", fs, version, `
+Hashes two directory trees at the same time and matches their files by
+path relative to each root, e.g. to check a copy or restore against its
+source. Filters apply to both trees. Exit code 0 means both trees hold
+the same files with the same content, 1 that they differ.
+
+Usage:
+  catmint compare [options] <dirA> <dirB>
+
+Examples:
+  catmint compare ./src /mnt/backup/src
+  catmint compare -fast -exclude '.git' /data /restore/data
+  catmint compare -a blake3 -report json ./a ./b
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint compare --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Error: please provide exactly two directories: catmint compare <dirA> <dirB>")
+		os.Exit(exitUsage)
+	}
+	dirA, dirB := fs.Arg(0), fs.Arg(1)
+
+	hashType := strings.TrimSpace(alg)
+	if code, err := keys.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+	if err := hashutil.CheckKey(hashType, keys.key); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		keyHint(err)
+		os.Exit(exitUsage)
+	}
+	if jobs < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -jobs/-j must not be negative")
+		os.Exit(exitUsage)
+	}
+	filter, err := filters.build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	format, err := detectReportFormat(reportFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(exitUsage)
+	}
+	for _, dir := range []string{dirA, dirB} {
+		if st, err := os.Stat(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		} else if !st.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: %s is not a directory\n", dir)
+			os.Exit(exitUsage)
+		}
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	opts := hashutil.CompareOptions{DirOptions: hashutil.DirOptions{Workers: jobs, Filter: filter, Key: keys.key}, Fast: fast}
+	report, err := hashutil.CompareDirectoriesContext(ctx, dirA, dirB, hashType, opts)
+	if err != nil && !report.Interrupted {
+		fmt.Fprintf(os.Stderr, "Gagal hashing direktori: %v\n", err)
+		if errors.Is(err, hashutil.ErrKeyRequired) {
+			keyHint(err)
+			os.Exit(exitUsage)
+		}
+		os.Exit(exitIO)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		printCompareReport(os.Stdout, report, dirA, dirB)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+	os.Exit(compareExitCode(report))
+}
+
+func printCompareReport(w io.Writer, report hashutil.CompareReport, dirA, dirB string) {
+	if report.Interrupted {
+		fmt.Fprintln(w, "⚠ Interrupted: comparison was cancelled, the report below is incomplete.")
+	}
+	fmt.Fprintf(w, "A: %s\nB: %s\n", dirA, dirB)
+	fmt.Fprintf(w, "Summary: %d identical, %d differing, %d only in A, %d only in B, %d unreadable\n",
+		len(report.Identical), len(report.Differing), len(report.OnlyInA), len(report.OnlyInB), len(report.Unreadable))
+
+	if len(report.Differing) > 0 {
+		fmt.Fprintln(w, "\n❌ Differing:")
+		for _, e := range report.Differing {
+			if e.Reason != "" {
+				fmt.Fprintf(w, "- %s (%s)\n", e.Path, e.Reason)
+			} else {
+				fmt.Fprintf(w, "- %s (A %s, B %s)\n", e.Path, e.HashA, e.HashB)
+			}
+		}
+	}
+	if len(report.OnlyInA) > 0 {
+		fmt.Fprintln(w, "\n❌ Only in A:")
+		for _, e := range report.OnlyInA {
+			fmt.Fprintf(w, "- %s\n", e.Path)
+		}
+	}
+	if len(report.OnlyInB) > 0 {
+		fmt.Fprintln(w, "\n❌ Only in B:")
+		for _, e := range report.OnlyInB {
+			fmt.Fprintf(w, "- %s\n", e.Path)
+		}
+	}
+	if len(report.Unreadable) > 0 {
+		fmt.Fprintln(w, "\n❌ Unreadable:")
+		for _, e := range report.Unreadable {
+			fmt.Fprintf(w, "- %s: %s\n", e.Path, e.Error)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"catmint/hashutil"
+	"catmint/internal"
+)
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		format     string
+		oldRoot    string
+		newRoot    string
+		pubKeyPath string
+	)
+
+	fs.StringVar(&format, "format", "text", "Output format: text, json, unified")
+	fs.StringVar(&oldRoot, "old-root", "", "Root the paths of the old manifest are relative to (default: the root in its header)")
+	fs.StringVar(&newRoot, "new-root", "", "Root the paths of the new manifest are relative to (default: the root in its header)")
+	fs.StringVar(&pubKeyPath, "pubkey", "", "Ed25519 public key: only trust manifests whose signature validates (default: $"+pubKeyEnv+")")
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("diff", fs, version, `
+Compares two manifests (any mix of .json, .ndjson, .csv, .tsv, .txt and
+checksum files) without touching the disk, and lists added, removed,
+modified, renamed and copied entries. A file is renamed when a removed
+entry's digest reappears under a new path; further new paths with that
+digest, or with the digest of an unchanged entry, are copies.
+Exit code 0 means the manifests describe the same files, 1 that they differ.
+
+Usage:
+  catmint diff [options] <old-manifest> <new-manifest>
+
+Examples:
+  catmint diff baseline-week41.json baseline-week42.json
+  catmint diff -format unified SHA256SUMS.old hash.csv
+  catmint diff -format json -old-root /mnt/old -new-root /mnt/new old.txt new.txt
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint diff --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Error: please provide exactly two manifests: catmint diff <old> <new>")
+		os.Exit(exitUsage)
+	}
+	switch format {
+	case "text", "json", "unified":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported -format %q. Please use text, json or unified.\n", format)
+		os.Exit(exitUsage)
+	}
+
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+	older := loadDiffManifest(oldPath, oldRoot, pubKeyPath)
+	newer := loadDiffManifest(newPath, newRoot, pubKeyPath)
+
+	diff := hashutil.DiffManifests(older, newer)
+	var err error
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	case "unified":
+		printUnifiedDiff(os.Stdout, diff, oldPath, newPath)
+	default:
+		printDiff(os.Stdout, diff)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+	if !diff.Empty() {
+		os.Exit(exitIntegrity)
+	}
+}
+
+// loadDiffManifest loads one side of a diff with paths relative to its root.
+func loadDiffManifest(path, root, pubKeyPath string) []hashutil.HashResult {
+	m, err := loadReference(path, "", pubKeyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal memuat referensi hash %s: %v\n", path, err)
+		os.Exit(referenceExitCode(err))
+	}
+	// Paths are only comparable relative to the root they were hashed from,
+	// whether it was given as an absolute or a relative path.
+	if root == "" {
+		root = m.Header.EntryRoot()
+	}
+	return hashutil.RebaseResults(m.Entries, root)
+}
+
+func printDiff(w io.Writer, diff hashutil.ManifestDiff) {
+	fmt.Fprintf(w, "Summary: %d added, %d removed, %d modified, %d renamed, %d copied, %d unchanged\n",
+		len(diff.Added), len(diff.Removed), len(diff.Modified), len(diff.Renamed), len(diff.Copied), diff.Unchanged)
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintln(w, "\nAdded:")
+		for _, e := range diff.Added {
+			fmt.Fprintf(w, "+ %s\n", e.Path)
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintln(w, "\nRemoved:")
+		for _, e := range diff.Removed {
+			fmt.Fprintf(w, "- %s\n", e.Path)
+		}
+	}
+	if len(diff.Modified) > 0 {
+		fmt.Fprintln(w, "\nModified:")
+		for _, e := range diff.Modified {
+			if e.OldHash != e.NewHash {
+				fmt.Fprintf(w, "~ %s (%s -> %s)\n", e.Path, e.OldHash, e.NewHash)
+			} else {
+				fmt.Fprintf(w, "~ %s\n", e.Path)
+			}
+			printChanges(w, e.Changes)
+		}
+	}
+	if len(diff.Renamed) > 0 {
+		fmt.Fprintln(w, "\nRenamed:")
+		for _, e := range diff.Renamed {
+			fmt.Fprintf(w, "> %s -> %s\n", e.OldPath, e.Path)
+		}
+	}
+	if len(diff.Copied) > 0 {
+		fmt.Fprintln(w, "\nCopied:")
+		for _, e := range diff.Copied {
+			fmt.Fprintf(w, "= %s -> %s\n", e.OldPath, e.Path)
+		}
+	}
+}
+
+// printUnifiedDiff writes the differences as -/+ checksum lines in path order,
+// like a unified diff of two sorted SHA256SUMS files.
+func printUnifiedDiff(w io.Writer, diff hashutil.ManifestDiff, oldName, newName string) {
+	type hunk struct {
+		path  string
+		lines []string
+	}
+	var hunks []hunk
+	for _, e := range diff.Added {
+		hunks = append(hunks, hunk{e.Path, []string{"+" + hashutil.FormatGNULine(e.NewHash, e.Path)}})
+	}
+	for _, e := range diff.Removed {
+		hunks = append(hunks, hunk{e.Path, []string{"-" + hashutil.FormatGNULine(e.OldHash, e.Path)}})
+	}
+	for _, e := range diff.Modified {
+		lines := []string{"-" + hashutil.FormatGNULine(e.OldHash, e.Path), "+" + hashutil.FormatGNULine(e.NewHash, e.Path)}
+		for _, c := range e.Changes {
+			lines = append(lines, "# "+e.Path+" "+c)
+		}
+		hunks = append(hunks, hunk{e.Path, lines})
+	}
+	for _, e := range diff.Renamed {
+		hunks = append(hunks, hunk{e.Path, []string{
+			"# rename " + e.OldPath + " -> " + e.Path,
+			"-" + hashutil.FormatGNULine(e.OldHash, e.OldPath),
+			"+" + hashutil.FormatGNULine(e.NewHash, e.Path),
+		}})
+	}
+	for _, e := range diff.Copied {
+		hunks = append(hunks, hunk{e.Path, []string{
+			"# copy " + e.OldPath + " -> " + e.Path,
+			"+" + hashutil.FormatGNULine(e.NewHash, e.Path),
+		}})
+	}
+	if len(hunks) == 0 {
+		return
+	}
+	sort.SliceStable(hunks, func(i, j int) bool { return hunks[i].path < hunks[j].path })
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		for _, line := range h.lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
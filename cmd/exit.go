@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"catmint/hashutil"
+)
+
+// Exit codes shared by every command so scripts can react without parsing output.
+const (
+	exitOK          = 0   // everything verified / produced successfully
+	exitIntegrity   = 1   // hash mismatch, missing or unexpected files, bad signature
+	exitUsage       = 2   // invalid command, flags or arguments
+	exitIO          = 3   // a file, directory or network resource could not be read or written
+	exitParse       = 4   // a reference file or response could not be parsed
+	exitInterrupted = 130 // cancelled by SIGINT/SIGTERM (128 + SIGINT)
+)
+
+// referenceExitCode classifies a LoadHashReference error: failing to open or
+// read the file is an I/O error, a signature that does not validate is an
+// integrity failure, anything else means its content is invalid.
+func referenceExitCode(err error) int {
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &pathErr):
+		return exitIO
+	case errors.Is(err, hashutil.ErrBadSignature):
+		return exitIntegrity
+	case errors.Is(err, hashutil.ErrSignedManifest):
+		return exitUsage
+	default:
+		return exitParse
+	}
+}
+
+// reportExitCode maps a directory verification report to an exit code.
+// Integrity problems take precedence over unreadable files.
+func reportExitCode(report hashutil.VerifyReport) int {
+	switch {
+	case report.Interrupted:
+		return exitInterrupted
+	case len(report.Mismatched) > 0 || len(report.MetaChanged) > 0 || len(report.Missing) > 0 || len(report.Extra) > 0 ||
+		len(report.Renamed) > 0 || len(report.Copied) > 0:
+		return exitIntegrity
+	case len(report.Unreadable) > 0:
+		return exitIO
+	default:
+		return exitOK
+	}
+}
+
+// compareExitCode maps a directory comparison to an exit code like reportExitCode.
+func compareExitCode(report hashutil.CompareReport) int {
+	switch {
+	case report.Interrupted:
+		return exitInterrupted
+	case len(report.Differing) > 0 || len(report.OnlyInA) > 0 || len(report.OnlyInB) > 0:
+		return exitIntegrity
+	case len(report.Unreadable) > 0:
+		return exitIO
+	default:
+		return exitOK
+	}
+}
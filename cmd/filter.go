@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"catmint/hashutil"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// filterFlags holds the directory filter options shared by hash and verify.
+type filterFlags struct {
+	include      stringList
+	exclude      stringList
+	ignoreFile   string
+	noIgnoreFile bool
+	maxDepth     int
+	skipHidden   bool
+	minSize      string
+	maxSize      string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	f := &filterFlags{}
+	fs.Var(&f.include, "include", "Directory mode: only hash files matching this glob (repeatable, supports **)")
+	fs.Var(&f.exclude, "exclude", "Directory mode: skip files/directories matching this glob (repeatable, supports **)")
+	fs.StringVar(&f.ignoreFile, "ignore-file", "", "Directory mode: gitignore-style ignore file (default: <dir>/"+hashutil.IgnoreFileName+" if present)")
+	fs.BoolVar(&f.noIgnoreFile, "no-ignore-file", false, "Directory mode: don't read "+hashutil.IgnoreFileName)
+	fs.IntVar(&f.maxDepth, "max-depth", 0, "Directory mode: maximum depth of files to hash, 1 = only the top directory (default: unlimited)")
+	fs.BoolVar(&f.skipHidden, "skip-hidden", false, "Directory mode: skip hidden files and directories (names starting with '.')")
+	fs.StringVar(&f.minSize, "min-size", "", "Directory mode: skip files smaller than this size (e.g. 512, 10K, 5M, 1G)")
+	fs.StringVar(&f.maxSize, "max-size", "", "Directory mode: skip files larger than this size (e.g. 512, 10K, 5M, 1G)")
+	return f
+}
+
+func (f *filterFlags) build() (*hashutil.Filter, error) {
+	if f.maxDepth < 0 {
+		return nil, fmt.Errorf("-max-depth must not be negative")
+	}
+	minSize, err := parseSize(f.minSize)
+	if err != nil {
+		return nil, fmt.Errorf("-min-size: %v", err)
+	}
+	maxSize, err := parseSize(f.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("-max-size: %v", err)
+	}
+	if maxSize > 0 && minSize > maxSize {
+		return nil, fmt.Errorf("-min-size must not be larger than -max-size")
+	}
+
+	return &hashutil.Filter{
+		Include:      f.include,
+		Exclude:      f.exclude,
+		IgnoreFile:   f.ignoreFile,
+		NoIgnoreFile: f.noIgnoreFile,
+		MaxDepth:     f.maxDepth,
+		SkipHidden:   f.skipHidden,
+		MinSize:      minSize,
+		MaxSize:      maxSize,
+	}, nil
+}
+
+// parseSize parses a byte count with an optional K, M, G or T suffix (powers of 1024).
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1 << 30
+	case strings.HasSuffix(value, "T"):
+		multiplier = 1 << 40
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
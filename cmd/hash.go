@@ -22,6 +22,14 @@ func runHash(args []string) {
 		dirPath    string
 		alg        string
 		outputFile string
+		format     string
+		tag        bool
+		jobs       int
+		relative   bool
+		meta       bool
+		policyPath string
+		merkle     bool
+		modes      bool
 	)
 
 	// file flags
@@ -33,11 +41,36 @@ func runHash(args []string) {
 	fs.StringVar(&dirPath, "d", "", "Alias for -dir")
 
 	// algorithm flags
-	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm: sha256, sha512, sha1, md5, sha3-256, blake3")
+	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm(s), comma separated: sha256, sha512, sha1, md5, sha3-256, blake3, hmac-<alg>, blake3-keyed")
 	fs.StringVar(&alg, "a", "sha256", "Alias for -alg")
 
+	// key for keyed algorithms
+	keys := addKeyFlags(fs)
+
+	// manifest flags
+	fs.BoolVar(&relative, "relative", false, "Directory mode: record paths relative to -dir with forward slashes (portable manifest)")
+
+	fs.BoolVar(&merkle, "merkle", false, "Directory mode: print a single Merkle root digest for the whole tree (implies -relative)")
+	fs.BoolVar(&modes, "merkle-modes", false, "With -merkle: include file permissions in the tree")
+
+	fs.BoolVar(&meta, "meta", false, "Record size, mode, uid/gid, mtime, inode/link count and symlink target with each hash")
+
+	fs.StringVar(&policyPath, "policy", "", "Directory mode: policy file selecting the algorithm and ignored paths per pattern (implies -meta)")
+
+	// filter flags
+	filters := addFilterFlags(fs)
+
+	// cache flags
+	caches := addCacheFlags(fs)
+
+	// concurrency flags
+	fs.IntVar(&jobs, "jobs", 0, "Number of files hashed in parallel in directory mode (default: number of CPUs)")
+	fs.IntVar(&jobs, "j", 0, "Alias for -jobs")
+
 	// output flags
-	fs.StringVar(&outputFile, "o", "", "Output file (supports .txt, .json, .csv)")
+	fs.StringVar(&outputFile, "o", "", "Output file (supports .txt, .json, .ndjson, .csv, .tsv, or coreutils checksum names like SHA256SUMS, *.sha256)")
+	fs.StringVar(&format, "format", "", "Output format for stdout or -o: txt, json, ndjson, csv, tsv, coreutils, bsd (default: from the -o name, txt for stdout)")
+	fs.BoolVar(&tag, "tag", false, "With coreutils output: write BSD style 'SHA256 (file) = hash' lines")
 
 	// Help for this command
 	for _, a := range args {
@@ -47,7 +80,20 @@ Examples:
   catmint hash -f test.txt -a sha256
   catmint hash -f test.txt -alg sha256 -o hash.txt
   catmint hash -d ./myfolder -alg sha512 -o hash.json
-`)
+  catmint hash -d ./myfolder -j 8
+  catmint hash -d ./myfolder -relative -o manifest.json
+  catmint hash -d ./release -merkle -o manifest.json
+  catmint hash -d ./myfolder -exclude '.git' -exclude '**/*.tmp' -max-size 1G
+  catmint hash -d ./dataset -cache .catmint-cache.json -o hash.json
+  catmint hash -d /etc -meta -o baseline.json
+  catmint hash -d /etc -policy catmint.policy -relative -o baseline.json
+  catmint hash -d ./myfolder -a sha256,md5,blake3 -o hash.csv
+  catmint hash -d ./myfolder -o SHA256SUMS
+  catmint hash -d ./myfolder -a sha512 -tag -o checksums.sha512
+  catmint hash -d . -format ndjson | jq -r .hash
+  catmint hash -d . -format coreutils > SHA256SUMS
+  CATMINT_HMAC_KEY=secret catmint hash -d ./myfolder -a hmac-sha256 -o hash.json
+`+policyUsage)
 			return
 		}
 	}
@@ -55,7 +101,7 @@ Examples:
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "Run 'catmint hash --help' for usage.")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	hashType := strings.TrimSpace(alg)
@@ -63,92 +109,261 @@ Examples:
 	if filePath == "" && dirPath == "" {
 		fmt.Fprintln(os.Stderr, "Error: please provide -file/-f or -dir/-d")
 		fmt.Fprintln(os.Stderr, "Run 'catmint hash --help' for usage.")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 	if filePath != "" && dirPath != "" {
 		fmt.Fprintln(os.Stderr, "Error: use only one of -file/-f or -dir/-d")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
-	outputFormat, err := detectOutputFormat(outputFile)
+	outputFormat, err := detectOutputFormat(outputFile, format)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+	if tag {
+		if outputFormat != "coreutils" && outputFormat != "bsd" {
+			fmt.Fprintln(os.Stderr, "Error: -tag requires coreutils output (-format coreutils or a checksum -o file such as SHA256SUMS, *.sha256)")
+			os.Exit(exitUsage)
+		}
+		outputFormat = "bsd"
 	}
 
-	if _, err := hashutil.GetHasher(hashType); err != nil {
+	if code, err := keys.apply(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(code)
+	}
+	algs, err := hashutil.ParseAlgorithms(hashType)
+	if err == nil {
+		err = hashutil.CheckKey(hashType, keys.key)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		keyHint(err)
+		os.Exit(exitUsage)
+	}
+	if outputFormat == "coreutils" && len(algs) > 1 {
+		fmt.Fprintln(os.Stderr, "Error: coreutils output holds a single algorithm; use -tag (BSD style) for several")
+		os.Exit(exitUsage)
+	}
+
+	if jobs < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -jobs/-j must not be negative")
+		os.Exit(exitUsage)
+	}
+	filter, err := filters.build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if (merkle || modes) && dirPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -merkle requires -dir/-d")
+		os.Exit(exitUsage)
+	}
+	merkle = merkle || modes
+	dirOpts := hashutil.DirOptions{Workers: jobs, RelativePaths: relative || merkle, Filter: filter, Metadata: meta || modes, Key: keys.key}
+	if policyPath != "" && dirPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -policy requires -dir/-d")
+		os.Exit(exitUsage)
+	}
+	if _, code, err := loadPolicy(policyPath, &dirOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+	cache, code, err := caches.open(&dirOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	// With -o every result goes straight into the output file as it arrives;
+	// only -merkle needs the whole list in memory.
+	var out *output.ResultFile
+	if outputFile != "" {
+		root := dirPath
+		if root == "" {
+			root = filePath
+		}
+		out, err = output.CreateResultFile(outputFile, outputFormat, output.Options{
+			Meta:   dirOpts.Metadata || dirOpts.Policy != nil,
+			Header: newManifestHeader(fs, root, hashType),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		}
+		// The manifest, its temporary file and a partial one left by an
+		// interrupted run may live inside the hashed tree.
+		dirOpts.OwnFiles = append(dirOpts.OwnFiles, outputFile, out.PartialPath())
+	}
+	// Without -o results stream to stdout in the chosen format, flushed per result.
+	var stdout output.ResultWriter
+	if out == nil && !merkle {
+		if stdout, err = output.NewResultWriter(os.Stdout, outputFormat, output.Options{Meta: dirOpts.Metadata || dirOpts.Policy != nil}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	abort := func(code int) {
+		if out != nil {
+			out.Abort()
+		}
+		os.Exit(code)
 	}
 
 	var results []hashutil.HashResult
+	var writeErr error
+	produced := 0
+	emit := func(res hashutil.HashResult) {
+		produced++
+		if merkle {
+			results = append(results, res)
+		}
+		switch {
+		case out != nil:
+			if err := out.Write(res); err != nil && writeErr == nil {
+				writeErr = err
+			}
+		case stdout != nil:
+			err := stdout.Write(res)
+			if err == nil {
+				err = stdout.Flush()
+			}
+			if err != nil && writeErr == nil {
+				writeErr = err
+			}
+		}
+	}
+
 	hadError := false
+	failedFiles := 0
+	cacheMismatches := 0
+	interrupted := false
 	usedStreamingOutput := false
 
 	// File mode
 	if filePath != "" {
-		result, err := hashutil.GenerateFileHash(filePath, hashType)
-		if err != nil {
+		result, err := hashutil.GenerateKeyedFileHash(ctx, filePath, hashType, keys.key)
+		if err != nil && ctx.Err() != nil {
+			interrupted = true
+		} else if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			hadError = true
 		} else {
-			results = append(results, result)
+			if meta {
+				if result.Meta, err = hashutil.FileMetadata(filePath); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					hadError = true
+				}
+			}
+			if !hadError {
+				emit(result)
+			}
 		}
 	}
 
 	// Dir mode
 	if dirPath != "" {
-		if outputFile != "" {
-			dirResults, err := hashutil.GenerateDirHash(dirPath, hashType, nil, nil)
-			if err != nil {
+		errorCount := 0
+		usedStreamingOutput = out == nil && !merkle
+		dirOpts.Discard = true
+
+		_, err := hashutil.GenerateDirHashContext(ctx, dirPath, hashType, dirOpts, emit,
+			func(path string, err error) {
+				if reportCacheMismatch(path, err) {
+					cacheMismatches++
+					return
+				}
+				fmt.Fprintf(os.Stderr, "Gagal hash file %s: %v\n", path, err)
+				errorCount++
+			},
+		)
+
+		if err != nil && ctx.Err() != nil {
+			// what was hashed so far is kept and finalized below
+			interrupted = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saat menjelajah direktori: %v\n", err)
+			hadError = true
+		}
+		if usedStreamingOutput {
+			// Keep machine-readable stdout clean: the summary only joins txt output.
+			summary := os.Stderr
+			if outputFormat == "txt" {
+				summary = os.Stdout
+				stdout.Flush()
+			}
+			fmt.Fprintf(summary, "\nSummary: %d success, %d failed\n", produced, errorCount)
+		}
+		failedFiles += errorCount
+	}
+
+	saveCache(cache)
+
+	if stdout != nil {
+		if err := stdout.Close(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing output: %v\n", writeErr)
+		abort(exitIO)
+	}
+
+	if interrupted {
+		// Never replace a complete baseline at -o with an incomplete manifest.
+		if out != nil && produced > 0 {
+			if err := out.ClosePartial(); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				hadError = true
 			} else {
-				results = append(results, dirResults...)
-			}
-		} else {
-			successCount := 0
-			errorCount := 0
-			usedStreamingOutput = true
-
-			_, err := hashutil.GenerateDirHash(dirPath, hashType,
-				func(res hashutil.HashResult) {
-					fmt.Printf("%s hash of file %s: %s\n", res.HashType, res.FilePath, res.Hash)
-					successCount++
-				},
-				func(path string, err error) {
-					fmt.Fprintf(os.Stderr, "Gagal hash file %s: %v\n", path, err)
-					errorCount++
-				},
-			)
-
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error saat menjelajah direktori: %v\n", err)
-				hadError = true
+				fmt.Fprintf(os.Stderr, "Saved %d partial hash result(s) to %s; %s was left unchanged\n", produced, out.PartialPath(), outputFile)
 			}
-			fmt.Printf("\nSummary: %d success, %d failed\n", successCount, errorCount)
+		} else if out != nil {
+			out.Abort()
 		}
+		fmt.Fprintln(os.Stderr, "Interrupted: hashing was cancelled before all files were processed; results are incomplete.")
+		os.Exit(exitInterrupted)
 	}
 
 	if hadError {
 		fmt.Fprintln(os.Stderr, "Run 'catmint hash --help' for usage.")
-		os.Exit(1)
+		abort(exitIO)
 	}
 
-	if len(results) == 0 && !usedStreamingOutput {
+	if produced == 0 && !usedStreamingOutput {
 		fmt.Fprintln(os.Stderr, "Error: no results produced")
-		os.Exit(1)
+		abort(exitIO)
 	}
 
-	if outputFile != "" {
-		if err := output.SaveResultsToFile(results, outputFile, outputFormat); err != nil {
+	if out != nil {
+		if err := out.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitIO)
 		}
-		fmt.Printf("Saved %d hash result(s) to %s\n", len(results), outputFile)
-	} else if !usedStreamingOutput {
-		for _, result := range results {
-			fmt.Printf("%s hash of file %s: %s\n", result.HashType, result.FilePath, result.Hash)
+		fmt.Printf("Saved %d hash result(s) to %s\n", produced, outputFile)
+	}
+
+	if merkle {
+		if failedFiles > 0 {
+			fmt.Fprintf(os.Stderr, "Error: Merkle root not computed: %d file(s) could not be hashed\n", failedFiles)
+			os.Exit(exitIO)
+		}
+		if err := printMerkleRoot(results, dirPath, hashType, hashutil.MerkleOptions{Modes: modes, Key: keys.key}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
 		}
 	}
+
+	if cacheMismatches > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: %d file(s) changed content without a metadata change\n", cacheMismatches)
+		os.Exit(exitIntegrity)
+	}
+
+	// Some files could not be read: the output is usable but incomplete.
+	if failedFiles > 0 {
+		os.Exit(exitIO)
+	}
 }
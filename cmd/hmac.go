@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"catmint/hashutil"
+)
+
+// hmacKeyEnv names the environment variable holding the key for keyed algorithms.
+const hmacKeyEnv = "CATMINT_HMAC_KEY"
+
+// keyFlags holds the key source of the keyed algorithms, shared by hash and verify.
+type keyFlags struct {
+	path string
+	// key is the loaded key, passed explicitly to every keyed hash.
+	key []byte
+}
+
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	k := &keyFlags{}
+	fs.StringVar(&k.path, "hmac-key", "", "Key file for hmac-<alg> and blake3-keyed (default: the value of $"+hmacKeyEnv+")")
+	return k
+}
+
+// apply loads the key from -hmac-key or CATMINT_HMAC_KEY, if any, for the keyed
+// algorithms. On error the exit code to use is returned as well.
+func (k *keyFlags) apply() (int, error) {
+	var key []byte
+	if strings.TrimSpace(k.path) != "" {
+		var err error
+		if key, err = hashutil.LoadHMACKey(k.path); err != nil {
+			return exitIO, err
+		}
+	} else if env := os.Getenv(hmacKeyEnv); env != "" {
+		key = []byte(env)
+	}
+	k.key = key
+	return exitOK, nil
+}
+
+// keyHint explains how to supply a key when err says one is missing.
+func keyHint(err error) {
+	if errors.Is(err, hashutil.ErrKeyRequired) {
+		fmt.Fprintln(os.Stderr, "Provide the key with -hmac-key <file> or $"+hmacKeyEnv+".")
+	}
+}
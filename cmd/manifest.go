@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"catmint/hashutil"
+)
+
+// headerOptions are the hash flags recorded in a manifest header: the ones
+// that decide which files are listed and what is recorded for them.
+var headerOptions = map[string]bool{
+	"relative": true, "merkle": true, "merkle-modes": true, "meta": true, "policy": true,
+	"include": true, "exclude": true, "ignore-file": true, "no-ignore-file": true,
+	"max-depth": true, "skip-hidden": true, "min-size": true, "max-size": true,
+}
+
+// newManifestHeader describes a manifest about to be written for root.
+func newManifestHeader(fs *flag.FlagSet, root, hashType string) *hashutil.ManifestHeader {
+	h := &hashutil.ManifestHeader{
+		Tool:       "catmint " + version,
+		Created:    time.Now().UTC().Format(time.RFC3339),
+		Root:       root,
+		Algorithms: hashType,
+	}
+	if abs, err := filepath.Abs(root); err == nil && abs != root {
+		h.Root, h.WalkRoot = abs, root
+	}
+	if algs, err := hashutil.ParseAlgorithms(hashType); err == nil {
+		h.Algorithms = strings.Join(algs, ",")
+	}
+	if host, err := os.Hostname(); err == nil {
+		h.Host = host
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if headerOptions[f.Name] {
+			if h.Options == nil {
+				h.Options = make(map[string]string)
+			}
+			h.Options[f.Name] = f.Value.String()
+		}
+	})
+	return h
+}
+
+// warnManifestHeader prints a warning for every way this run differs from the
+// one that wrote the manifest: the -a algorithm when given explicitly, the
+// root being verified and the catmint version.
+func warnManifestHeader(h *hashutil.ManifestHeader, fs *flag.FlagSet, root, hashType string) {
+	if h == nil {
+		return
+	}
+	explicitAlg := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "a" || f.Name == "alg" {
+			explicitAlg = true
+		}
+	})
+	if explicitAlg && h.Algorithms != "" && !containsAlgorithm(h.Algorithms, hashType) {
+		fmt.Fprintf(os.Stderr, "Warning: manifest was recorded with %s, not %s\n", h.Algorithms, hashType)
+	}
+	if abs, err := filepath.Abs(root); err == nil && h.Root != "" && filepath.Clean(h.Root) != abs {
+		fmt.Fprintf(os.Stderr, "Warning: manifest was recorded for root %s, verifying %s\n", h.Root, abs)
+	}
+	if tool := "catmint " + version; h.Tool != "" && h.Tool != tool {
+		fmt.Fprintf(os.Stderr, "Warning: manifest was written by %s, this is %s\n", h.Tool, tool)
+	}
+}
+
+func containsAlgorithm(list, alg string) bool {
+	for _, a := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(alg)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"catmint/hashutil"
+)
+
+// merkleAlgorithm returns the algorithm a Merkle tree is built with: the first of hashType.
+func merkleAlgorithm(hashType string) string {
+	algs, err := hashutil.ParseAlgorithms(hashType)
+	if err != nil || len(algs) == 0 {
+		return hashType
+	}
+	return algs[0]
+}
+
+func printMerkleRoot(results []hashutil.HashResult, dirPath, hashType string, opts hashutil.MerkleOptions) error {
+	alg := merkleAlgorithm(hashType)
+	tree, err := hashutil.BuildMerkleTree(results, alg, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("MERKLE-%s root of %s: %s\n", strings.ToUpper(alg), dirPath, tree.Hash)
+	return nil
+}
+
+// printMerkleChanges prints the differing subtrees found by DiffMerkleTrees, indented by depth.
+func printMerkleChanges(changes []hashutil.MerkleChange) {
+	for _, c := range changes {
+		depth := 0
+		if c.Path != "." {
+			depth = strings.Count(c.Path, "/") + 1
+		}
+		name := c.Path
+		if c.Dir {
+			name += "/"
+		}
+		fmt.Printf("%s%-8s %s\n", strings.Repeat("  ", depth), c.Change, name)
+	}
+}
+
+// verifyMerkleRoot hashes dirPath, compares its Merkle root with expectedRoot and
+// returns the exit code. On a mismatch the tree of reference (if any) is used to
+// pinpoint the differing subdirectories and files.
+func verifyMerkleRoot(ctx context.Context, dirPath, expectedRoot string, reference []hashutil.HashResult, hashType string, modes bool, opts hashutil.DirOptions) int {
+	alg := merkleAlgorithm(hashType)
+	failed := 0
+	results, err := hashutil.GenerateDirHashContext(ctx, dirPath, alg, opts, nil, func(path string, err error) {
+		if reportCacheMismatch(path, err) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Gagal hash file %s: %v\n", path, err)
+		failed++
+	})
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Interrupted: verification was cancelled.")
+		return exitInterrupted
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal hashing direktori: %v\n", err)
+		return exitIO
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: Merkle root not computed: %d file(s) could not be hashed\n", failed)
+		return exitIO
+	}
+
+	merkleOpts := hashutil.MerkleOptions{Modes: modes, Key: opts.Key}
+	tree, err := hashutil.BuildMerkleTree(results, alg, merkleOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitIO
+	}
+	if hashutil.HashEqual(tree.Hash, expectedRoot) {
+		fmt.Printf("Directory %s: Merkle root matches!\n", dirPath)
+		return exitOK
+	}
+
+	fmt.Printf("Directory %s: Merkle root does not match. Expected: %s, Got: %s\n", dirPath, expectedRoot, tree.Hash)
+	if len(reference) == 0 {
+		fmt.Println("Run again with -ref <manifest> to find the differing subdirectories.")
+		return exitIntegrity
+	}
+	refTree, err := hashutil.BuildMerkleTree(reference, alg, merkleOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot build a Merkle tree from the reference: %v\n", err)
+		return exitIntegrity
+	}
+	if !hashutil.HashEqual(refTree.Hash, expectedRoot) {
+		fmt.Fprintf(os.Stderr, "Warning: the reference manifest does not match the expected root either (its root is %s); differences are relative to the manifest\n", refTree.Hash)
+	}
+	fmt.Println("\nDiffering entries:")
+	printMerkleChanges(hashutil.DiffMerkleTrees(refTree, tree))
+	return exitIntegrity
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+
+	"catmint/hashutil"
+)
+
+// policyUsage documents the policy file format in command help.
+const policyUsage = `
+Policy files (-policy):
+  One rule per line, the last matching line wins; # starts a comment.
+    NAME = <rules>          define a named rule set (DEFAULT applies to unmatched files)
+    <pattern> <rules>       check matching files with a rule set or inline rules
+    !<pattern>              ignore matching files and directories
+  Rules are joined with '+': hash, size, perms, owner, mtime, inode, growing
+  (log files: size may only grow), or an algorithm name such as sha512.
+  Patterns are relative to -dir and use the -include/-exclude glob syntax.
+
+    NORMAL  = hash+size+perms+owner+mtime
+    etc/**      NORMAL
+    etc/ssl/**  sha512+perms+owner
+    var/log/**  growing+perms+owner
+    !var/cache/**
+`
+
+// loadPolicy reads the -policy file and applies it to opts. It returns nil when
+// no policy was given; on error the exit code to use is returned as well.
+func loadPolicy(path string, opts *hashutil.DirOptions) (*hashutil.Policy, int, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, exitOK, nil
+	}
+	policy, err := hashutil.LoadPolicy(path)
+	if err != nil {
+		return nil, referenceExitCode(err), err
+	}
+	opts.Policy = policy
+	return policy, exitOK, nil
+}
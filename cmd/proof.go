@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"catmint/hashutil"
+	"catmint/internal"
+)
+
+func runProof(args []string) {
+	fs := flag.NewFlagSet("proof", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		dirPath    string
+		refPath    string
+		relPath    string
+		alg        string
+		modes      bool
+		outputFile string
+		policyPath string
+		pubKeyPath string
+		sigPath    string
+
+		proofPath string
+		filePath  string
+		root      string
+	)
+
+	// proof generation flags
+	fs.StringVar(&dirPath, "dir", "", "Directory to build the tree from")
+	fs.StringVar(&dirPath, "d", "", "Alias for -dir")
+	fs.StringVar(&refPath, "ref", "", "Build the tree from a manifest with root-relative paths ('hash -relative') instead of -dir")
+	fs.StringVar(&relPath, "path", "", "Path of the file to prove, relative to the root")
+	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm of the tree")
+	fs.StringVar(&alg, "a", "sha256", "Alias for -alg")
+	fs.BoolVar(&modes, "merkle-modes", false, "The tree includes file permissions ('hash -merkle-modes')")
+	fs.StringVar(&outputFile, "o", "", "Write the proof to this file instead of stdout")
+	fs.StringVar(&policyPath, "policy", "", "With -dir: policy file whose ignored paths are left out, as with 'hash -policy'")
+	fs.StringVar(&pubKeyPath, "pubkey", "", "Ed25519 public key: only trust -ref if its signature validates (default: $"+pubKeyEnv+")")
+	fs.StringVar(&sigPath, "sig", "", "With -pubkey: detached signature of -ref (default: <ref>.sig)")
+
+	// filter flags, to rebuild a tree hashed with -include/-exclude/...
+	filters := addFilterFlags(fs)
+
+	// proof verification flags
+	fs.StringVar(&proofPath, "verify", "", "Proof file to check -file against -root")
+	fs.StringVar(&filePath, "file", "", "With -verify: the file to check")
+	fs.StringVar(&filePath, "f", "", "Alias for -file")
+	fs.StringVar(&root, "root", "", "With -verify: the trusted Merkle root (from 'hash -merkle')")
+
+	keys := addKeyFlags(fs)
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("proof", fs, version, `
+Emits an inclusion proof showing that one file belongs to a directory tree
+whose Merkle root was published with 'catmint hash -d <dir> -merkle', and
+checks a file plus proof against that root without the full manifest.
+
+Examples:
+  catmint proof -d ./release -path bin/tool -o tool.proof.json
+  catmint proof -d ./release -exclude '*.tmp' -path bin/tool -o tool.proof.json
+  catmint proof -ref manifest.json -path bin/tool -o tool.proof.json
+  catmint proof -verify tool.proof.json -f ./tool -root <ROOT>
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint proof --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if code, err := keys.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	// Verify mode
+	if proofPath != "" {
+		if filePath == "" || strings.TrimSpace(root) == "" {
+			fmt.Fprintln(os.Stderr, "Error: -verify requires -file/-f and -root")
+			os.Exit(exitUsage)
+		}
+		data, err := os.ReadFile(proofPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		}
+		var proof hashutil.MerkleProof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", proofPath, err)
+			os.Exit(exitParse)
+		}
+		result, err := hashutil.GenerateKeyedFileHash(ctx, filePath, proof.HashType, keys.key)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintln(os.Stderr, "Interrupted: verification was cancelled.")
+				os.Exit(exitInterrupted)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			keyHint(err)
+			os.Exit(exitIO)
+		}
+		if err := hashutil.VerifyKeyedMerkleProof(proof, result.Hash, root, keys.key); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if errors.Is(err, hashutil.ErrProofMismatch) {
+				os.Exit(exitIntegrity)
+			}
+			os.Exit(exitParse)
+		}
+		fmt.Printf("File %s: included in the tree as %s\n", filePath, proof.Path)
+		return
+	}
+
+	// Generate mode
+	if (dirPath == "") == (refPath == "") {
+		fmt.Fprintln(os.Stderr, "Error: please provide one of -dir/-d or -ref (or -verify to check a proof)")
+		fmt.Fprintln(os.Stderr, "Run 'catmint proof --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if strings.TrimSpace(relPath) == "" {
+		fmt.Fprintln(os.Stderr, "Error: -path is required")
+		os.Exit(exitUsage)
+	}
+	hashType := merkleAlgorithm(strings.TrimSpace(alg))
+	if _, err := hashutil.GetKeyedHasher(hashType, keys.key); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		keyHint(err)
+		os.Exit(exitUsage)
+	}
+
+	if refPath != "" && policyPath != "" {
+		fmt.Fprintln(os.Stderr, "Error: -policy requires -dir/-d")
+		os.Exit(exitUsage)
+	}
+	if sigPath != "" && pubKeyPath == "" && os.Getenv(pubKeyEnv) == "" {
+		fmt.Fprintln(os.Stderr, "Error: -sig requires -pubkey or $"+pubKeyEnv)
+		os.Exit(exitUsage)
+	}
+	filter, err := filters.build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	var results []hashutil.HashResult
+	if refPath != "" {
+		manifest, err := loadReference(refPath, sigPath, pubKeyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal memuat referensi hash: %v\n", err)
+			os.Exit(referenceExitCode(err))
+		}
+		// The tree is built from root-relative paths.
+		results = hashutil.RebaseResults(manifest.Entries, manifest.Header.EntryRoot())
+	} else {
+		failed := 0
+		opts := hashutil.DirOptions{RelativePaths: true, Metadata: modes, Filter: filter, Key: keys.key}
+		if _, code, err := loadPolicy(policyPath, &opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(code)
+		}
+		results, err = hashutil.GenerateDirHashContext(ctx, dirPath, hashType, opts, nil, func(path string, err error) {
+			fmt.Fprintf(os.Stderr, "Gagal hash file %s: %v\n", path, err)
+			failed++
+		})
+		if err != nil && ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted: hashing was cancelled.")
+			os.Exit(exitInterrupted)
+		}
+		if err != nil || failed > 0 {
+			fmt.Fprintf(os.Stderr, "Error: the tree of %s could not be hashed completely\n", dirPath)
+			os.Exit(exitIO)
+		}
+	}
+
+	proof, err := hashutil.BuildMerkleProof(results, relPath, hashType, hashutil.MerkleOptions{Modes: modes, Key: keys.key})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitParse)
+	}
+	data, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+
+	if outputFile == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(outputFile, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+	fmt.Printf("Saved inclusion proof of %s (root %s) to %s\n", proof.Path, proof.Root, outputFile)
+}
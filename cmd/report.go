@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"catmint/hashutil"
+)
+
+func detectReportFormat(format string) (string, error) {
+	switch format {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("Error: report format not supported: %s. Please use text or json.", format)
+	}
+}
+
+func renderVerifyReport(w io.Writer, report hashutil.VerifyReport, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "text":
+		printVerifyReport(w, report)
+		return nil
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+func printVerifyReport(w io.Writer, report hashutil.VerifyReport) {
+	if report.Interrupted {
+		fmt.Fprintln(w, "⚠ Interrupted: verification was cancelled, the report below is incomplete and missing files were not checked.")
+	}
+	fmt.Fprintf(w, "Summary: %d unchanged, %d modified, %d metadata changed, %d missing, %d new, %d renamed, %d copied, %d unreadable\n",
+		len(report.Matched), len(report.Mismatched), len(report.MetaChanged), len(report.Missing), len(report.Extra),
+		len(report.Renamed), len(report.Copied), len(report.Unreadable))
+
+	if len(report.Mismatched) > 0 {
+		fmt.Fprintln(w, "\n❌ Modified:")
+		for _, e := range report.Mismatched {
+			fmt.Fprintf(w, "- %s (expected %s, got %s)\n", e.FilePath, e.Expected, e.Actual)
+			printChanges(w, e.Changes)
+		}
+	}
+
+	if len(report.MetaChanged) > 0 {
+		fmt.Fprintln(w, "\n⚠ Metadata changed (content unchanged):")
+		for _, e := range report.MetaChanged {
+			fmt.Fprintf(w, "- %s\n", e.FilePath)
+			printChanges(w, e.Changes)
+		}
+	}
+
+	if len(report.Missing) > 0 {
+		fmt.Fprintln(w, "\n❌ Missing from disk:")
+		for _, e := range report.Missing {
+			fmt.Fprintf(w, "- %s\n", e.FilePath)
+		}
+	}
+
+	if len(report.Extra) > 0 {
+		fmt.Fprintln(w, "\n❌ Not found in reference:")
+		for _, e := range report.Extra {
+			fmt.Fprintf(w, "- %s\n", e.FilePath)
+		}
+	}
+
+	if len(report.Renamed) > 0 {
+		fmt.Fprintln(w, "\n⚠ Renamed/moved (content unchanged):")
+		for _, e := range report.Renamed {
+			fmt.Fprintf(w, "- %s -> %s\n", e.From, e.FilePath)
+		}
+	}
+
+	if len(report.Copied) > 0 {
+		fmt.Fprintln(w, "\n⚠ Copied (content of a reference file):")
+		for _, e := range report.Copied {
+			fmt.Fprintf(w, "- %s -> %s\n", e.From, e.FilePath)
+		}
+	}
+
+	if len(report.Unreadable) > 0 {
+		fmt.Fprintln(w, "\n❌ Unreadable:")
+		for _, e := range report.Unreadable {
+			fmt.Fprintf(w, "- %s: %s\n", e.FilePath, e.Error)
+		}
+	}
+}
+
+func printChanges(w io.Writer, changes []string) {
+	for _, c := range changes {
+		fmt.Fprintf(w, "    %s\n", c)
+	}
+}
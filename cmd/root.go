@@ -15,7 +15,7 @@ func Execute() {
 	// No args -> show root usage
 	if len(os.Args) < 2 {
 		printRootUsage()
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	switch os.Args[1] {
@@ -31,7 +31,7 @@ func Execute() {
 	if strings.HasPrefix(os.Args[1], "-") {
 		fmt.Fprint(os.Stderr, "Error: missing command. Use 'catmint <command> [options]'.\n")
 		printRootUsage()
-		os.Exit(2)
+		os.Exit(exitUsage)
 	}
 
 	cmd := os.Args[1]
@@ -42,12 +42,26 @@ func Execute() {
 		runHash(args)
 	case "verify":
 		runVerify(args)
+	case "cache":
+		runCache(args)
+	case "keygen":
+		runKeygen(args)
+	case "sign":
+		runSign(args)
+	case "proof":
+		runProof(args)
+	case "diff":
+		runDiff(args)
+	case "compare":
+		runCompare(args)
+	case "dupes":
+		runDupes(args)
 	case "show-update":
 		runShowUpdate(args)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", cmd)
 		printRootUsage()
-		os.Exit(2)
+		os.Exit(exitUsage)
 	}
 }
 
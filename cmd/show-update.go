@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
+
+	"catmint/internal"
 )
 
 const (
@@ -19,7 +22,18 @@ type githubRelease struct {
 	HTMLURL string `json:"html_url"`
 }
 
-func runShowUpdate(_ []string) {
+func runShowUpdate(args []string) {
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("show-update", flag.NewFlagSet("show-update", flag.ContinueOnError), version, "")
+			return
+		}
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: show-update takes no arguments, got %q\n", args[0])
+		os.Exit(exitUsage)
+	}
+
 	url := fmt.Sprintf(
 		"https://api.github.com/repos/%s/%s/releases/latest",
 		githubOwner,
@@ -32,20 +46,20 @@ func runShowUpdate(_ []string) {
 
 	resp, err := client.Get(url)
 	if err != nil {
-		fmt.Println("Unable to check updates (offline?)")
-		return
+		fmt.Fprintln(os.Stderr, "Unable to check updates (offline?)")
+		os.Exit(exitIO)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		fmt.Fprintf(os.Stderr, "GitHub API error: %s\n", resp.Status)
-		os.Exit(1)
+		os.Exit(exitIO)
 	}
 
 	var release githubRelease
 	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to parse response: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitParse)
 	}
 
 	current := version
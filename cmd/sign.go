@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"catmint/hashutil"
+	"catmint/internal"
+)
+
+// pubKeyEnv names the environment variable holding the default public key path for verify.
+const pubKeyEnv = "CATMINT_PUBKEY"
+
+func runKeygen(args []string) {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		keyPath string
+		pubPath string
+	)
+
+	fs.StringVar(&keyPath, "o", "catmint.key", "Private key output file (PEM, PKCS#8)")
+	fs.StringVar(&pubPath, "pub", "", "Public key output file (default: <private key>.pub)")
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("keygen", fs, version, `
+Generates an Ed25519 key pair for signing manifests. Existing files are never
+overwritten. Keep the private key away from the machines being monitored.
+
+Examples:
+  catmint keygen
+  catmint keygen -o /secure/catmint.key -pub catmint.pub
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint keygen --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if strings.TrimSpace(keyPath) == "" {
+		fmt.Fprintln(os.Stderr, "Error: -o must not be empty")
+		os.Exit(exitUsage)
+	}
+	if pubPath == "" {
+		pubPath = keyPath + ".pub"
+	}
+
+	pub, err := hashutil.GenerateKeyPair(keyPath, pubPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+	fmt.Printf("Private key: %s\n", keyPath)
+	fmt.Printf("Public key : %s (key id %s)\n", pubPath, hashutil.KeyID(pub))
+}
+
+func runSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		keyPath    string
+		refPath    string
+		outputFile string
+		embed      bool
+	)
+
+	fs.StringVar(&keyPath, "key", "", "Ed25519 private key created with 'catmint keygen'")
+	fs.StringVar(&refPath, "ref", "", "Manifest to sign (.txt, .json, .csv or a checksum file)")
+	fs.BoolVar(&embed, "embed", false, "Write a JSON envelope containing the manifest and its signature instead of a detached .sig file")
+	fs.StringVar(&outputFile, "o", "", "Output file (default: <ref>.sig, or <ref>.signed.json with -embed)")
+
+	// Help for this command
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			internal.PrintCommandUsage("sign", fs, version, `
+Check a signed manifest with 'catmint verify -d <dir> -ref <manifest> -pubkey <key.pub>'.
+A detached signature is looked up next to the manifest (<ref>.sig) unless -sig is given.
+
+Examples:
+  catmint sign -key catmint.key -ref baseline.json
+  catmint sign -key catmint.key -ref baseline.json -embed -o baseline.signed.json
+`)
+			return
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run 'catmint sign --help' for usage.")
+		os.Exit(exitUsage)
+	}
+	if strings.TrimSpace(keyPath) == "" || strings.TrimSpace(refPath) == "" {
+		fmt.Fprintln(os.Stderr, "Error: please provide -key and -ref")
+		fmt.Fprintln(os.Stderr, "Run 'catmint sign --help' for usage.")
+		os.Exit(exitUsage)
+	}
+
+	priv, err := hashutil.LoadPrivateKey(keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(referenceExitCode(err))
+	}
+
+	// Make sure we sign something verify can load later.
+	if _, err := hashutil.LoadHashReference(refPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal memuat referensi hash: %v\n", err)
+		os.Exit(referenceExitCode(err))
+	}
+	data, err := os.ReadFile(refPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+
+	var out []byte
+	if embed {
+		if outputFile == "" {
+			outputFile = strings.TrimSuffix(refPath, filepath.Ext(refPath)) + ".signed.json"
+		}
+		if out, err = json.MarshalIndent(hashutil.NewSignedManifest(refPath, data, priv), "", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		}
+	} else {
+		if outputFile == "" {
+			outputFile = refPath + hashutil.SignatureExt
+		}
+		out = []byte(hashutil.FormatSignature(data, priv))
+	}
+
+	if err := os.WriteFile(outputFile, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitIO)
+	}
+	fmt.Printf("Signed %s -> %s\n", refPath, outputFile)
+}
+
+// loadReference loads the -ref manifest, requiring a valid signature when a
+// public key is configured through -pubkey or CATMINT_PUBKEY.
+func loadReference(refPath, sigPath, pubKeyPath string) (hashutil.Manifest, error) {
+	if pubKeyPath == "" {
+		pubKeyPath = os.Getenv(pubKeyEnv)
+	}
+	if pubKeyPath == "" {
+		return hashutil.LoadManifest(refPath)
+	}
+	pub, err := hashutil.LoadPublicKey(pubKeyPath)
+	if err != nil {
+		return hashutil.Manifest{}, err
+	}
+	return hashutil.LoadSignedManifest(refPath, sigPath, pub)
+}
+
+// loadChecksumFile is the loadReference of verify -c: it returns every line of
+// the checksum file and the number of improperly formatted ones.
+func loadChecksumFile(path, sigPath, pubKeyPath string) ([]hashutil.HashResult, int, error) {
+	if pubKeyPath == "" {
+		pubKeyPath = os.Getenv(pubKeyEnv)
+	}
+	if pubKeyPath == "" {
+		return hashutil.LoadChecksumFile(path)
+	}
+	pub, err := hashutil.LoadPublicKey(pubKeyPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return hashutil.LoadSignedChecksumFile(path, sigPath, pub)
+}
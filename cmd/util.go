@@ -4,17 +4,45 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	"catmint/hashutil"
+	"catmint/output"
 )
 
-func detectOutputFormat(outputFile string) (string, error) {
+// detectOutputFormat returns the explicit -format if given, otherwise the
+// format implied by the -o file name (txt for stdout).
+func detectOutputFormat(outputFile, format string) (string, error) {
+	if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+		for _, f := range output.Formats {
+			if f == format {
+				return format, nil
+			}
+		}
+		return "", fmt.Errorf("Error: unsupported -format %q. Please use %s.", format, strings.Join(output.Formats, ", "))
+	}
 	if strings.TrimSpace(outputFile) == "" {
 		return "txt", nil
 	}
 	ext := strings.ToLower(filepath.Ext(outputFile))
 	switch ext {
-	case ".json", ".csv", ".txt":
+	case ".json", ".ndjson", ".csv", ".tsv", ".txt":
 		return strings.TrimPrefix(ext, "."), nil
-	default:
-		return "", fmt.Errorf("Error: Output format not supported. Please use .txt, .json, or .csv.")
+	case ".jsonl":
+		return "ndjson", nil
+	}
+	if isChecksumOutput(outputFile) {
+		return "coreutils", nil
+	}
+	return "", fmt.Errorf("Error: Output format not supported. Please use .txt, .json, .ndjson, .csv, .tsv, a checksum file name (SHA256SUMS, *.sha256, *.md5, *.sum) or -format.")
+}
+
+// isChecksumOutput reports whether outputFile is named like a coreutils checksum
+// file, e.g. SHA256SUMS, MD5SUMS, release.sha256 or files.sum.
+func isChecksumOutput(outputFile string) bool {
+	base := strings.ToLower(filepath.Base(outputFile))
+	if strings.HasSuffix(base, "sums") || filepath.Ext(base) == ".sum" {
+		return true
 	}
+	_, err := hashutil.ParseAlgorithms(strings.TrimPrefix(filepath.Ext(base), "."))
+	return err == nil
 }
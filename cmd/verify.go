@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -21,7 +22,21 @@ func runVerify(args []string) {
 		dirPath      string
 		expectedHash string
 		refPath      string
+		rootPath     string
 		alg          string
+		reportFormat string
+		policyPath   string
+		modes        bool
+		checkpoint   string
+		resume       bool
+		pubKeyPath   string
+		sigPath      string
+		jobs         int
+
+		checkFile     string
+		quiet         bool
+		status        bool
+		ignoreMissing bool
 	)
 
 	// file flags
@@ -32,16 +47,53 @@ func runVerify(args []string) {
 	fs.StringVar(&dirPath, "dir", "", "Path of the directory to verify recursively")
 	fs.StringVar(&dirPath, "d", "", "Alias for -dir")
 
-	// expected hash for single file verify
-	fs.StringVar(&expectedHash, "hash", "", "Expected hash to verify against the file")
+	// expected hash for single file verify, or Merkle root for a directory
+	fs.StringVar(&expectedHash, "hash", "", "Expected hash of -file, or Merkle root of -dir (from 'hash -merkle')")
+	fs.BoolVar(&modes, "merkle-modes", false, "With -dir and -hash: the root was built with 'hash -merkle-modes'")
 
 	// reference file for directory verify against reference
 	fs.StringVar(&refPath, "ref", "", "Path to file containing reference hashes (.txt, .json, .csv)")
 
+	// signature flags
+	fs.StringVar(&pubKeyPath, "pubkey", "", "Ed25519 public key: only trust -ref or -check if its signature validates (default: $"+pubKeyEnv+")")
+	fs.StringVar(&sigPath, "sig", "", "With -pubkey: detached signature of -ref or -check (default: <file>.sig)")
+
+	// root used to re-anchor reference paths
+	fs.StringVar(&rootPath, "root", "", "Directory mode: root that reference paths are relative to (default: the root recorded in the manifest, else -dir)")
+
 	// algorithm flags
-	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm: sha256, sha512, sha1, md5, sha3-256")
+	fs.StringVar(&alg, "alg", "sha256", "Hash algorithm: sha256, sha512, sha1, md5, sha3-256, blake3, hmac-<alg>, blake3-keyed (directory mode: only for reference entries without a hash type)")
 	fs.StringVar(&alg, "a", "sha256", "Alias for -alg")
 
+	fs.StringVar(&policyPath, "policy", "", "Directory mode: policy file selecting which attributes are checked per pattern")
+
+	// key for keyed algorithms
+	keys := addKeyFlags(fs)
+
+	// checksum file (sha256sum -c style) flags
+	fs.StringVar(&checkFile, "check", "", "Read checksums from a coreutils/BSD style checksum file and check them")
+	fs.StringVar(&checkFile, "c", "", "Alias for -check")
+	fs.BoolVar(&quiet, "quiet", false, "With -check: don't print OK for each successfully verified file")
+	fs.BoolVar(&status, "status", false, "With -check: don't output anything, the exit code shows success")
+	fs.BoolVar(&ignoreMissing, "ignore-missing", false, "With -check: don't fail or report status for missing files")
+
+	// filter flags
+	filters := addFilterFlags(fs)
+
+	// cache flags
+	caches := addCacheFlags(fs)
+
+	// concurrency flags
+	fs.IntVar(&jobs, "jobs", 0, "Number of files hashed in parallel in directory mode (default: number of CPUs)")
+	fs.IntVar(&jobs, "j", 0, "Alias for -jobs")
+
+	// checkpoint flags
+	fs.StringVar(&checkpoint, "checkpoint", "", "Directory mode: record progress in this file so an interrupted run can be resumed (default with -resume: <ref>.checkpoint)")
+	fs.BoolVar(&resume, "resume", false, "Directory mode: continue from the -checkpoint file instead of starting over")
+
+	// report flags
+	fs.StringVar(&reportFormat, "report", "text", "Directory verify report format: text, json")
+
 	// Help for this command
 	for _, a := range args {
 		if a == "-h" || a == "--help" {
@@ -50,13 +102,46 @@ Modes:
   1) Single file verify:
      catmint verify -f <path> -hash <EXPECTED_HASH> [-a sha256]
 
-  2) Directory verify against reference file:
+  2) Checksum file verify (like sha256sum -c):
+     catmint verify -c SHA256SUMS [--quiet] [--status] [--ignore-missing]
+
+  3) Directory verify against reference file:
      catmint verify -d <path> -ref <hashes.json|csv|txt> [-a sha256]
+     Each file is hashed with the algorithm recorded in the reference.
+     Paths are matched relative to -d; use -root when the reference was
+     recorded somewhere else (e.g. -root /old/mount/myfolder).
+     Files skipped by -include/-exclude/.catmintignore are not reported missing.
+     If the reference was created with 'hash -meta', metadata-only changes
+     (mode, owner, mtime, ...) are reported separately from content changes.
+     With -policy only the attributes its rules select are checked, and
+     ignored paths are neither hashed nor reported missing.
+     With -pubkey (or $`+pubKeyEnv+`) the reference is only used if its
+     signature from 'catmint sign' validates; otherwise verify stops with exit 1.
+     With -checkpoint, progress is saved every few seconds and on interrupt;
+     rerun the same command with -resume to skip the files already checked.
+     The checkpoint is removed once the verification completes.
+     A new file with the content of a missing reference entry is reported as
+     renamed/moved; further new files with reference content as copies.
+     A warning is printed when the manifest header records another root,
+     algorithm (-a) or catmint version than this run.
+
+  4) Directory verify against a Merkle root (from 'catmint hash -d <dir> -merkle'):
+     catmint verify -d <path> -hash <ROOT> [-ref manifest.json] [-a sha256]
+     With -ref, a mismatch is narrowed down to the differing subdirectories
+     and files by comparing against the tree of the manifest.
 
 Examples:
   catmint verify -f test.txt -hash <HASH>
   catmint verify -d ./myfolder -ref hash.json
-`)
+  catmint verify -d ./myfolder -ref hash.json -report json
+  catmint verify -d /etc -ref baseline.json -policy catmint.policy
+  catmint verify -d /etc -ref baseline.json -pubkey catmint.key.pub
+  catmint verify --check SHA256SUMS --ignore-missing
+  catmint verify -d ./myfolder -ref hash.json -hmac-key catmint.hmac
+  catmint verify -d ./release -hash <ROOT> -ref manifest.json
+  catmint verify -d /archive -ref archive.json -checkpoint archive.ckpt
+  catmint verify -d /archive -ref archive.json -checkpoint archive.ckpt -resume
+`+policyUsage)
 			return
 		}
 	}
@@ -64,62 +149,213 @@ Examples:
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "Run 'catmint verify --help' for usage.")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	hashType := strings.TrimSpace(alg)
 
+	if code, err := keys.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(code)
+	}
+
 	// Validate algo early
-	if _, err := hashutil.GetHasher(hashType); err != nil {
+	if _, err := hashutil.GetKeyedHasher(hashType, keys.key); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		keyHint(err)
+		os.Exit(exitUsage)
+	}
+
+	if jobs < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -jobs/-j must not be negative")
+		os.Exit(exitUsage)
+	}
+	filter, err := filters.build()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	if sigPath != "" && pubKeyPath == "" && os.Getenv(pubKeyEnv) == "" {
+		fmt.Fprintln(os.Stderr, "Error: -sig requires -pubkey or $"+pubKeyEnv)
+		os.Exit(exitUsage)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	// Checksum file mode
+	if checkFile != "" {
+		if filePath != "" || dirPath != "" {
+			fmt.Fprintln(os.Stderr, "Error: -check/-c cannot be combined with -file/-f or -dir/-d")
+			os.Exit(exitUsage)
+		}
+		code, err := runCheck(ctx, checkFile, checkOptions{
+			alg:           hashType,
+			quiet:         quiet,
+			status:        status,
+			ignoreMissing: ignoreMissing,
+			key:           keys.key,
+			pubKeyPath:    pubKeyPath,
+			sigPath:       sigPath,
+		})
+		if err != nil && ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted: verification was cancelled.")
+			os.Exit(exitInterrupted)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(referenceExitCode(err))
+		}
+		os.Exit(code)
 	}
 
 	// Validate mode selection
 	if filePath == "" && dirPath == "" {
 		fmt.Fprintln(os.Stderr, "Error: please provide -file/-f or -dir/-d")
 		fmt.Fprintln(os.Stderr, "Run 'catmint verify --help' for usage.")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 	if filePath != "" && dirPath != "" {
 		fmt.Fprintln(os.Stderr, "Error: use only one of -file/-f or -dir/-d")
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	if policyPath != "" && dirPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -policy requires -dir/-d")
+		os.Exit(exitUsage)
+	}
+	if (checkpoint != "" || resume) && (dirPath == "" || strings.TrimSpace(refPath) == "") {
+		fmt.Fprintln(os.Stderr, "Error: -checkpoint and -resume require -dir/-d with -ref")
+		os.Exit(exitUsage)
 	}
 
 	// Mode 1: Single file verify
 	if filePath != "" {
 		if strings.TrimSpace(expectedHash) == "" {
 			fmt.Fprintln(os.Stderr, "Error: -hash (expected hash) is required when using -file/-f")
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
-		if err := hashutil.VerifyFileHash(filePath, hashType, expectedHash); err != nil {
+		if err := hashutil.VerifyKeyedFileHash(ctx, filePath, hashType, expectedHash, keys.key); err != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintln(os.Stderr, "Interrupted: verification was cancelled.")
+				os.Exit(exitInterrupted)
+			}
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			if errors.Is(err, hashutil.ErrHashMismatch) {
+				os.Exit(exitIntegrity)
+			}
+			os.Exit(exitIO)
 		}
 		fmt.Printf("File %s: hash matches!\n", filePath)
 		return
 	}
 
-	// Mode 2: Directory verify against reference file
+	// Mode 2: Directory verify against reference file or Merkle root
 	if dirPath != "" {
-		if strings.TrimSpace(refPath) == "" {
-			fmt.Fprintln(os.Stderr, "Error: -ref is required when using -dir/-d")
-			os.Exit(1)
+		merkleRoot := strings.TrimSpace(expectedHash)
+		if strings.TrimSpace(refPath) == "" && merkleRoot == "" {
+			fmt.Fprintln(os.Stderr, "Error: -ref or -hash (Merkle root) is required when using -dir/-d")
+			os.Exit(exitUsage)
 		}
 
-		reference, err := hashutil.LoadHashReference(refPath)
+		var reference []hashutil.HashResult
+		var entryRoot string
+		if strings.TrimSpace(refPath) != "" {
+			manifest, err := loadReference(refPath, sigPath, pubKeyPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Gagal memuat referensi hash: %v\n", err)
+				os.Exit(referenceExitCode(err))
+			}
+			reference = manifest.Entries
+			entryRoot = manifest.Header.EntryRoot()
+			root := rootPath
+			if strings.TrimSpace(root) == "" {
+				root = dirPath
+			}
+			warnManifestHeader(manifest.Header, fs, root, hashType)
+		}
+
+		format, err := detectReportFormat(reportFormat)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Gagal memuat referensi hash: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitUsage)
+		}
+
+		// Compare in root-relative space so the reference can come from another
+		// checkout. Without -root the walk root recorded in the manifest is
+		// stripped, so the reference can also be verified from another directory.
+		if strings.TrimSpace(rootPath) == "" {
+			rootPath = dirPath
+			if entryRoot != "" {
+				rootPath = entryRoot
+			}
 		}
+		reference = hashutil.RebaseResults(reference, rootPath)
 
-		actual, err := hashutil.GenerateDirHash(dirPath, hashType, nil, nil)
+		// A reference and its signature kept inside the verified tree are not
+		// among its files.
+		refSig := sigPath
+		if refSig == "" && strings.TrimSpace(refPath) != "" {
+			refSig = refPath + hashutil.SignatureExt
+		}
+		dirOpts := hashutil.DirOptions{Workers: jobs, RelativePaths: true, Filter: filter, OwnFiles: []string{refPath, refSig}, Key: keys.key}
+		if _, code, err := loadPolicy(policyPath, &dirOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(code)
+		}
+		cache, code, err := caches.open(&dirOpts)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(code)
+		}
+
+		if merkleRoot != "" {
+			dirOpts.Metadata = modes
+			code := verifyMerkleRoot(ctx, dirPath, merkleRoot, reference, hashType, modes, dirOpts)
+			saveCache(cache)
+			os.Exit(code)
+		}
+
+		if resume && checkpoint == "" {
+			checkpoint = refPath + ".checkpoint"
+		}
+		if checkpoint != "" {
+			if dirOpts.Checkpoint, err = hashutil.OpenCheckpoint(checkpoint, resume); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(referenceExitCode(err))
+			}
+			if n := dirOpts.Checkpoint.Len(); n > 0 {
+				fmt.Fprintf(os.Stderr, "Resuming from %s: %d file(s) already checked\n", checkpoint, n)
+			}
+		}
+
+		report, err := hashutil.VerifyDirectoryContext(ctx, dirPath, hashType, reference, dirOpts)
+		saveCache(cache)
+		if err != nil && !report.Interrupted {
 			fmt.Fprintf(os.Stderr, "Gagal hashing direktori: %v\n", err)
-			os.Exit(1)
+			if errors.Is(err, hashutil.ErrKeyRequired) {
+				keyHint(err)
+				os.Exit(exitUsage)
+			}
+			if errors.Is(err, hashutil.ErrCheckpointMismatch) {
+				fmt.Fprintln(os.Stderr, "Run without -resume to start over.")
+				os.Exit(exitUsage)
+			}
+			os.Exit(exitIO)
+		}
+		if cp := dirOpts.Checkpoint; cp != nil {
+			if report.Interrupted {
+				fmt.Fprintf(os.Stderr, "Progress saved to %s; rerun with -resume to continue.\n", cp.Path())
+			} else if err := cp.Remove(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 		}
 
-		hashutil.CompareResults(actual, reference)
-		return
+		if err := renderVerifyReport(os.Stdout, report, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitIO)
+		}
+		os.Exit(reportExitCode(report))
 	}
 }
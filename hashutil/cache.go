@@ -0,0 +1,271 @@
+package hashutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const cacheVersion = 1
+
+// fileSys holds the inode level metadata that os.FileInfo does not expose portably.
+type fileSys struct {
+	Dev   uint64
+	Inode uint64
+	Nlink uint64
+	UID   uint32
+	GID   uint32
+}
+
+// CacheEntry is a digest remembered for a file together with the metadata it
+// was computed from. The entry is only trusted while that metadata is unchanged.
+type CacheEntry struct {
+	Path     string `json:"path"`
+	HashType string `json:"hash_type"`
+	Size     int64  `json:"size"`
+	ModTime  int64  `json:"mtime_ns"`
+	Inode    uint64 `json:"inode,omitempty"`
+	Hash     string `json:"hash"`
+}
+
+// CacheStats counts cache lookups during a run.
+type CacheStats struct {
+	Entries int
+	Hits    int
+	Misses  int
+}
+
+// CacheMismatchError is reported to onError in paranoid mode when a file's
+// content no longer matches its cached digest although size, mtime and inode
+// are unchanged (silent corruption or tampering with a restored mtime).
+type CacheMismatchError struct {
+	HashType string
+	Cached   string
+	Actual   string
+}
+
+func (e *CacheMismatchError) Error() string {
+	return fmt.Sprintf("content changed without metadata change (%s cached %s, now %s)", e.HashType, e.Cached, e.Actual)
+}
+
+// Cache is an on-disk map of file digests keyed by absolute path, size, mtime,
+// inode and algorithm. It is safe for concurrent use by the hashing workers.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	dirty   bool
+	hits    int
+	misses  int
+}
+
+type cacheFile struct {
+	Version int          `json:"version"`
+	Entries []CacheEntry `json:"entries"`
+}
+
+// OpenCache loads the cache stored at path. A missing file yields an empty cache.
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]CacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cache %s: %w", path, err)
+	}
+	if file.Version != cacheVersion {
+		return nil, fmt.Errorf("cache %s: unsupported version %d", path, file.Version)
+	}
+	for _, e := range file.Entries {
+		c.entries[cacheKey(e.Path, e.HashType)] = e
+	}
+	return c, nil
+}
+
+// Save writes the cache atomically (temporary file + rename) if it changed.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cacheFile{Version: cacheVersion, Entries: c.sortedEntries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Path returns the file the cache is stored in.
+func (c *Cache) Path() string {
+	return c.path
+}
+
+// Lookup returns the cached digest of path for hashType if the entry still
+// matches the file's size, mtime and inode.
+func (c *Cache) Lookup(path string, info os.FileInfo, hashType string) (string, bool) {
+	entry, ok := c.peek(path, info, hashType)
+	c.mu.Lock()
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	c.mu.Unlock()
+	return entry.Hash, ok
+}
+
+func (c *Cache) peek(path string, info os.FileInfo, hashType string) (CacheEntry, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[cacheKey(abs, hashType)]
+	c.mu.Unlock()
+	if !ok || !entry.matches(info) {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Store remembers hash as the hashType digest of path with its current metadata.
+func (c *Cache) Store(path string, info os.FileInfo, hashType, hash string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	entry := newCacheEntry(abs, info, hashType, hash)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := cacheKey(abs, hashType)
+	if old, ok := c.entries[key]; ok && old == entry {
+		return
+	}
+	c.entries[key] = entry
+	c.dirty = true
+}
+
+// Entries returns every cached entry sorted by path and algorithm.
+func (c *Cache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sortedEntries()
+}
+
+// Stats returns the number of entries and the hits/misses of this run.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Entries: len(c.entries), Hits: c.hits, Misses: c.misses}
+}
+
+// Prune removes entries whose file no longer exists or whose metadata changed,
+// and returns how many were removed.
+func (c *Cache) Prune() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, entry := range c.entries {
+		if !entry.Fresh() {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.dirty = true
+	}
+	return removed
+}
+
+func (c *Cache) sortedEntries() []CacheEntry {
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].HashType < entries[j].HashType
+	})
+	return entries
+}
+
+func newCacheEntry(abs string, info os.FileInfo, hashType, hash string) CacheEntry {
+	entry := CacheEntry{
+		Path:     abs,
+		HashType: strings.ToUpper(hashType),
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Hash:     hash,
+	}
+	if sys, ok := sysStat(info); ok {
+		entry.Inode = sys.Inode
+	}
+	return entry
+}
+
+// Fresh reports whether the file still exists with the recorded size, mtime and inode.
+func (e CacheEntry) Fresh() bool {
+	info, err := os.Stat(e.Path)
+	return err == nil && e.matches(info)
+}
+
+func (e CacheEntry) matches(info os.FileInfo) bool {
+	if info == nil || e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() {
+		return false
+	}
+	if sys, ok := sysStat(info); ok && e.Inode != sys.Inode {
+		return false
+	}
+	return true
+}
+
+func cacheKey(abs, hashType string) string {
+	return abs + "\x00" + strings.ToUpper(hashType)
+}
+
+// writeFileAtomic replaces path with data through a temporary file and a rename,
+// so an interrupted write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
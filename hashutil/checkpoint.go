@@ -0,0 +1,170 @@
+package hashutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const checkpointVersion = 1
+
+// CheckpointInterval is how often a running verification saves its checkpoint.
+const CheckpointInterval = 5 * time.Second
+
+// ErrCheckpointMismatch is returned (wrapped) when resuming from a checkpoint
+// written for another directory, reference, algorithm, key, policy or filter set.
+var ErrCheckpointMismatch = errors.New("checkpoint belongs to a different verification")
+
+// Checkpoint records which files a directory verification has already hashed
+// and their outcome, so an interrupted run can be resumed without rehashing
+// them. The final report is computed from the recorded outcomes plus the new
+// ones, so it is the same as that of an uninterrupted run.
+type Checkpoint struct {
+	path     string
+	state    checkpointFile
+	done     map[string]bool
+	lastSave time.Time
+}
+
+// CheckpointFailure is a file that could not be hashed.
+type CheckpointFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type checkpointFile struct {
+	Version     int                 `json:"version"`
+	Fingerprint string              `json:"fingerprint"`
+	Results     []HashResult        `json:"results"`
+	Failures    []CheckpointFailure `json:"failures"`
+}
+
+// OpenCheckpoint prepares the checkpoint at path. With resume the recorded
+// progress is loaded (a missing file starts from scratch); otherwise any
+// existing checkpoint is replaced on the next save.
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{path: path, state: checkpointFile{Version: checkpointVersion}, done: make(map[string]bool), lastSave: time.Now()}
+	if !resume {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if c.state.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s: unsupported version %d", path, c.state.Version)
+	}
+	for _, r := range c.state.Results {
+		c.done[r.FilePath] = true
+	}
+	for _, f := range c.state.Failures {
+		c.done[f.Path] = true
+	}
+	return c, nil
+}
+
+// Path returns the checkpoint file name.
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Len returns the number of files whose outcome is recorded.
+func (c *Checkpoint) Len() int {
+	return len(c.done)
+}
+
+// Save writes the checkpoint atomically.
+func (c *Checkpoint) Save() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	c.lastSave = time.Now()
+	return writeFileAtomic(c.path, data)
+}
+
+// Remove deletes the checkpoint once the verification has completed.
+func (c *Checkpoint) Remove() error {
+	err := os.Remove(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// bind ties the checkpoint to one verification, refusing recorded progress
+// that belongs to another one.
+func (c *Checkpoint) bind(fingerprint string) error {
+	if c.state.Fingerprint != "" && c.state.Fingerprint != fingerprint {
+		return fmt.Errorf("%s: %w", c.path, ErrCheckpointMismatch)
+	}
+	c.state.Fingerprint = fingerprint
+	return nil
+}
+
+// doneSnapshot returns a copy of the recorded file names.
+func (c *Checkpoint) doneSnapshot() map[string]bool {
+	done := make(map[string]bool, len(c.done))
+	for name := range c.done {
+		done[name] = true
+	}
+	return done
+}
+
+func (c *Checkpoint) addResult(r HashResult) error {
+	c.state.Results = append(c.state.Results, r)
+	c.done[r.FilePath] = true
+	return c.saveIfDue()
+}
+
+func (c *Checkpoint) addFailure(path string, err error) error {
+	c.state.Failures = append(c.state.Failures, CheckpointFailure{Path: path, Error: err.Error()})
+	c.done[path] = true
+	return c.saveIfDue()
+}
+
+func (c *Checkpoint) saveIfDue() error {
+	if time.Since(c.lastSave) < CheckpointInterval {
+		return nil
+	}
+	return c.Save()
+}
+
+// verifyFingerprint identifies a verification by everything that decides its
+// outcome: the directory, algorithms, key, reference, policy and walk options.
+// Only a digest of the key is recorded.
+func verifyFingerprint(dirPath, algs string, reference []HashResult, opts DirOptions) string {
+	if abs, err := filepath.Abs(dirPath); err == nil {
+		dirPath = abs
+	}
+	var keyDigest string
+	if len(opts.Key) > 0 {
+		sum := sha256.Sum256(opts.Key)
+		keyDigest = hex.EncodeToString(sum[:])
+	}
+	data, _ := json.Marshal(struct {
+		Dir           string             `json:"dir"`
+		Algorithms    string             `json:"algorithms"`
+		Key           string             `json:"key,omitempty"`
+		RelativePaths bool               `json:"relative_paths"`
+		Metadata      bool               `json:"metadata"`
+		Filter        *Filter            `json:"filter"`
+		Policy        *policyFingerprint `json:"policy,omitempty"`
+		Reference     []HashResult       `json:"reference"`
+	}{dirPath, algs, keyDigest, opts.RelativePaths, opts.Metadata, opts.Filter, opts.Policy.fingerprint(), reference})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
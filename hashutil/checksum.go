@@ -0,0 +1,198 @@
+package hashutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Readers and writers for the checksum files produced by GNU coreutils
+// (sha256sum and friends) in both the default and the BSD tagged style:
+//
+//	<hash>  <path>           (text mode)
+//	<hash> *<path>           (binary mode)
+//	SHA256 (<path>) = <hash> (--tag)
+
+// FormatGNULine formats a single "<hash>  <path>" line as written by sha256sum.
+func FormatGNULine(hash, path string) string {
+	name, escaped := escapeChecksumName(path)
+	line := strings.ToLower(hash) + "  " + name
+	if escaped {
+		line = "\\" + line
+	}
+	return line
+}
+
+// FormatBSDLine formats a single "ALG (<path>) = <hash>" line as written by sha256sum --tag.
+func FormatBSDLine(hashType, path, hash string) string {
+	name, escaped := escapeChecksumName(path)
+	line := strings.ToUpper(hashType) + " (" + name + ") = " + strings.ToLower(hash)
+	if escaped {
+		line = "\\" + line
+	}
+	return line
+}
+
+// parseChecksumLine parses a GNU or BSD style checksum line. hint is the algorithm
+// assumed for GNU lines, which do not name one; when empty it is guessed from the
+// digest length where that is unambiguous. Digests of the wrong length make the
+// line improperly formatted.
+func parseChecksumLine(line, hint string) (HashResult, bool) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	if r, ok := parseBSDLine(line); ok {
+		if !validDigestLength(r.HashType, r.Hash) {
+			return HashResult{}, false
+		}
+		if escaped {
+			r.FilePath = unescapeChecksumName(r.FilePath)
+		}
+		return r, true
+	}
+
+	// GNU: hex digest, a space, then a space (text) or '*' (binary), then the name.
+	end := 0
+	for end < len(line) && isHexDigit(line[end]) {
+		end++
+	}
+	if end == 0 || end+2 > len(line) || line[end] != ' ' || (line[end+1] != ' ' && line[end+1] != '*') {
+		return HashResult{}, false
+	}
+	name := line[end+2:]
+	if name == "" {
+		return HashResult{}, false
+	}
+	if escaped {
+		name = unescapeChecksumName(name)
+	}
+
+	hash := line[:end]
+	hashType := hint
+	if hashType == "" {
+		hashType = guessAlgorithm(hash)
+	}
+	if !validDigestLength(hashType, hash) {
+		return HashResult{}, false
+	}
+	return HashResult{FilePath: name, HashType: hashType, Hash: hash}, true
+}
+
+func parseBSDLine(line string) (HashResult, bool) {
+	open := strings.Index(line, " (")
+	closing := strings.LastIndex(line, ") = ")
+	if open <= 0 || closing < open+2 {
+		return HashResult{}, false
+	}
+	tag := line[:open]
+	if strings.ContainsAny(tag, " \t") {
+		return HashResult{}, false
+	}
+	hash := line[closing+4:]
+	if hash == "" || strings.IndexFunc(hash, func(r rune) bool { return r > 0x7f || !isHexDigit(byte(r)) }) >= 0 {
+		return HashResult{}, false
+	}
+	return HashResult{
+		FilePath: line[open+2 : closing],
+		HashType: strings.ToUpper(tag),
+		Hash:     hash,
+	}, true
+}
+
+// algorithmHint derives the algorithm from conventional checksum file names
+// such as SHA256SUMS, MD5SUMS or release.sha512.
+func algorithmHint(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	ext := strings.TrimPrefix(filepath.Ext(base), ".")
+	for _, candidate := range []string{strings.TrimSuffix(base, "sums"), strings.TrimSuffix(name, "sums"), ext} {
+		if candidate == "" {
+			continue
+		}
+		if checkAlgorithm(candidate) == nil {
+			return strings.ToUpper(candidate)
+		}
+	}
+	return ""
+}
+
+// isChecksumFileName reports whether path looks like a coreutils checksum file
+// (SHA256SUMS, foo.sha256, foo.md5, foo.sum, ...).
+func isChecksumFileName(path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return strings.HasSuffix(base, "sums") || filepath.Ext(base) == ".sum" || algorithmHint(path) != ""
+}
+
+// guessAlgorithm returns the algorithm implied by the digest length, or "" when
+// several supported algorithms share that length (e.g. 64 hex digits).
+func guessAlgorithm(hash string) string {
+	switch len(hash) {
+	case 32:
+		return "MD5"
+	case 40:
+		return "SHA1"
+	case 128:
+		return "SHA512"
+	default:
+		return ""
+	}
+}
+
+// validDigestLength reports whether hash is as long as a hashType digest, or
+// as any supported digest when hashType is unknown.
+func validDigestLength(hashType, hash string) bool {
+	if hashType == "" {
+		switch len(hash) {
+		case 32, 40, 64, 128:
+			return true
+		}
+		return false
+	}
+	name := strings.TrimPrefix(strings.ToLower(hashType), "hmac-")
+	if name == "blake3-keyed" {
+		name = "blake3"
+	}
+	h, err := newHasher(name)
+	if err != nil {
+		// Unsupported algorithms are reported when the file is hashed.
+		return true
+	}
+	return len(hash) == 2*h.Size()
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func escapeChecksumName(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+	return r.Replace(name), true
+}
+
+func unescapeChecksumName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) {
+			switch name[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package hashutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// compareSides names dirA and dirB in errors.
+var compareSides = [2]string{"A", "B"}
+
+// CompareEntry is the outcome for one relative path in a directory comparison.
+type CompareEntry struct {
+	Path     string `json:"path"`
+	HashType string `json:"hash_type,omitempty"`
+	HashA    string `json:"hash_a,omitempty"`
+	HashB    string `json:"hash_b,omitempty"`
+	// Reason explains a difference found without hashing, e.g. in fast mode
+	// "size 12 != 15".
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CompareReport is the result of comparing two directory trees.
+type CompareReport struct {
+	// Interrupted is set when the comparison was cancelled before both trees
+	// were hashed; the categories are incomplete in that case.
+	Interrupted bool `json:"interrupted,omitempty"`
+
+	Identical  []CompareEntry `json:"identical"`
+	Differing  []CompareEntry `json:"differing"`
+	OnlyInA    []CompareEntry `json:"only_in_a"`
+	OnlyInB    []CompareEntry `json:"only_in_b"`
+	Unreadable []CompareEntry `json:"unreadable"`
+}
+
+// OK reports whether both trees hold the same files with the same content.
+func (r CompareReport) OK() bool {
+	return !r.Interrupted && len(r.Differing) == 0 && len(r.OnlyInA) == 0 && len(r.OnlyInB) == 0 && len(r.Unreadable) == 0
+}
+
+// CompareOptions controls CompareDirectories. The directory options apply to
+// both trees; paths are always matched relative to each root.
+type CompareOptions struct {
+	DirOptions
+
+	// Fast lists both trees first and only hashes files present in both with
+	// the same size. Files of different size are reported as differing and
+	// files on one side only are reported without a hash.
+	Fast bool
+}
+
+// CompareDirectories hashes dirA and dirB and matches their files by relative path.
+func CompareDirectories(dirA, dirB, hashType string, opts CompareOptions) (CompareReport, error) {
+	return CompareDirectoriesContext(context.Background(), dirA, dirB, hashType, opts)
+}
+
+// CompareDirectoriesContext is like CompareDirectories but can be cancelled
+// through ctx. On cancellation the partial report is returned with Interrupted
+// set, along with ctx.Err().
+func CompareDirectoriesContext(ctx context.Context, dirA, dirB, hashType string, opts CompareOptions) (CompareReport, error) {
+	report := CompareReport{
+		Identical:  []CompareEntry{},
+		Differing:  []CompareEntry{},
+		OnlyInA:    []CompareEntry{},
+		OnlyInB:    []CompareEntry{},
+		Unreadable: []CompareEntry{},
+	}
+	dirOpts := opts.DirOptions
+	dirOpts.RelativePaths = true
+	dirOpts.Discard = false
+	dirOpts.Checkpoint = nil
+
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	onError := func(side string) func(string, error) {
+		return func(path string, err error) {
+			var mismatch *CacheMismatchError
+			if errors.As(err, &mismatch) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			failed[NormalizePath(path)] = true
+			report.Unreadable = append(report.Unreadable, CompareEntry{Path: path, Error: side + ": " + err.Error()})
+		}
+	}
+
+	if opts.Fast {
+		var sizes [2]map[string]int64
+		err := bothSides(func(i int, dir string) error {
+			var err error
+			sizes[i], err = listFileSizes(ctx, dir, dirOpts, onError(compareSides[i]))
+			return err
+		}, dirA, dirB)
+		if err != nil {
+			return finishCompare(ctx, report, err)
+		}
+		dirOpts.only = make(map[string]bool)
+		for name, sizeA := range sizes[0] {
+			sizeB, ok := sizes[1][name]
+			switch {
+			case failed[name]:
+			case !ok:
+				report.OnlyInA = append(report.OnlyInA, CompareEntry{Path: name})
+			case sizeA != sizeB:
+				report.Differing = append(report.Differing, CompareEntry{Path: name, Reason: fmt.Sprintf("size %d != %d", sizeA, sizeB)})
+			default:
+				dirOpts.only[name] = true
+			}
+		}
+		for name := range sizes[1] {
+			if _, ok := sizes[0][name]; !ok && !failed[name] {
+				report.OnlyInB = append(report.OnlyInB, CompareEntry{Path: name})
+			}
+		}
+	}
+
+	var results [2][]HashResult
+	err := bothSides(func(i int, dir string) error {
+		var err error
+		results[i], err = GenerateDirHashContext(ctx, dir, hashType, dirOpts, nil, onError(compareSides[i]))
+		return err
+	}, dirA, dirB)
+	if err != nil {
+		return finishCompare(ctx, report, err)
+	}
+
+	// A file that could not be read on one side is only reported as unreadable.
+	var a, b []HashResult
+	for _, r := range results[0] {
+		if !failed[NormalizePath(r.FilePath)] {
+			a = append(a, r)
+		}
+	}
+	for _, r := range results[1] {
+		if !failed[NormalizePath(r.FilePath)] {
+			b = append(b, r)
+		}
+	}
+	contentOnly := func(string) RuleSet { return RuleSet{Hash: true} }
+	matched := compareResults(b, a, contentOnly)
+	for _, e := range matched.Matched {
+		report.Identical = append(report.Identical, CompareEntry{Path: e.FilePath, HashType: e.HashType, HashA: e.Expected, HashB: e.Actual})
+	}
+	for _, e := range matched.Mismatched {
+		report.Differing = append(report.Differing, CompareEntry{Path: e.FilePath, HashType: e.HashType, HashA: e.Expected, HashB: e.Actual})
+	}
+	for _, e := range matched.Missing {
+		report.OnlyInA = append(report.OnlyInA, CompareEntry{Path: e.FilePath, HashType: e.HashType, HashA: e.Expected})
+	}
+	for _, e := range matched.Extra {
+		report.OnlyInB = append(report.OnlyInB, CompareEntry{Path: e.FilePath, HashType: e.HashType, HashB: e.Actual})
+	}
+	return finishCompare(ctx, report, nil)
+}
+
+// bothSides runs fn for dirA (i = 0) and dirB (i = 1) concurrently and
+// returns the first error.
+func bothSides(fn func(i int, dir string) error, dirA, dirB string) error {
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i, dir := range []string{dirA, dirB} {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			errs[i] = fn(i, dir)
+		}(i, dir)
+	}
+	wg.Wait()
+	if errs[0] != nil {
+		return errs[0]
+	}
+	return errs[1]
+}
+
+// listFileSizes returns the size of every file GenerateDirHash would hash in
+// dirPath, keyed by relative path.
+func listFileSizes(ctx context.Context, dirPath string, opts DirOptions, onError func(string, error)) (map[string]int64, error) {
+	filter, err := newPathFilter(dirPath, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	err = walkFiles(ctx, dirPath, filter, opts, func(path string, info os.FileInfo, err error) error {
+		name := opts.name(dirPath, path)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			// Hashing follows symlinks, so compare the size of the target.
+			info, err = os.Stat(path)
+		}
+		if err != nil {
+			onError(name, err)
+			return nil
+		}
+		sizes[name] = info.Size()
+		return nil
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	return sizes, err
+}
+
+func finishCompare(ctx context.Context, report CompareReport, err error) (CompareReport, error) {
+	if err != nil && ctx.Err() != nil {
+		report.Interrupted = true
+	} else if err != nil {
+		return CompareReport{}, err
+	}
+	for _, entries := range [][]CompareEntry{report.Identical, report.Differing, report.OnlyInA, report.OnlyInB, report.Unreadable} {
+		sortCompareEntries(entries)
+	}
+	return report, err
+}
+
+func sortCompareEntries(entries []CompareEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+}
@@ -0,0 +1,75 @@
+package hashutil
+
+import "sort"
+
+// DiffEntry is one difference between two manifests. OldPath is only set for
+// renames and copies; OldHash is empty for added and NewHash for removed entries.
+type DiffEntry struct {
+	Path     string   `json:"path"`
+	OldPath  string   `json:"old_path,omitempty"`
+	HashType string   `json:"hash_type,omitempty"`
+	OldHash  string   `json:"old_hash,omitempty"`
+	NewHash  string   `json:"new_hash,omitempty"`
+	Changes  []string `json:"changes,omitempty"`
+}
+
+// ManifestDiff lists what changed from an old manifest to a new one.
+type ManifestDiff struct {
+	Added    []DiffEntry `json:"added"`
+	Removed  []DiffEntry `json:"removed"`
+	Modified []DiffEntry `json:"modified"`
+	Renamed  []DiffEntry `json:"renamed"`
+	// Copied holds added entries with the content of an entry of the old
+	// manifest (OldPath) that is still present or was renamed.
+	Copied []DiffEntry `json:"copied"`
+	// Unchanged counts the entries that are identical in both manifests.
+	Unchanged int `json:"unchanged"`
+}
+
+// Empty reports whether both manifests describe the same files.
+func (d ManifestDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.Renamed) == 0 &&
+		len(d.Copied) == 0
+}
+
+// DiffManifests compares two manifests without touching the disk. Entries are
+// matched by normalized path; a file whose content or recorded metadata differs
+// is modified, and a removed entry whose digest reappears under a new path is
+// reported as renamed instead of removed plus added. Further new paths with
+// that digest, or with the digest of an unchanged entry, are copies.
+func DiffManifests(old, new []HashResult) ManifestDiff {
+	report := compareResults(new, old, func(string) RuleSet { return allAttributes })
+	diff := ManifestDiff{
+		Added:     []DiffEntry{},
+		Removed:   []DiffEntry{},
+		Modified:  []DiffEntry{},
+		Renamed:   []DiffEntry{},
+		Copied:    []DiffEntry{},
+		Unchanged: len(report.Matched),
+	}
+
+	report.pairMoves()
+	for _, e := range report.Renamed {
+		diff.Renamed = append(diff.Renamed, DiffEntry{Path: e.FilePath, OldPath: e.From, HashType: e.HashType, OldHash: e.Expected, NewHash: e.Actual})
+	}
+	for _, e := range report.Copied {
+		diff.Copied = append(diff.Copied, DiffEntry{Path: e.FilePath, OldPath: e.From, HashType: e.HashType, OldHash: e.Expected, NewHash: e.Actual})
+	}
+	for _, e := range report.Missing {
+		diff.Removed = append(diff.Removed, DiffEntry{Path: e.FilePath, HashType: e.HashType, OldHash: e.Expected})
+	}
+	for _, e := range report.Extra {
+		diff.Added = append(diff.Added, DiffEntry{Path: e.FilePath, HashType: e.HashType, NewHash: e.Actual})
+	}
+	for _, e := range append(report.Mismatched, report.MetaChanged...) {
+		diff.Modified = append(diff.Modified, DiffEntry{
+			Path:     e.FilePath,
+			HashType: e.HashType,
+			OldHash:  e.Expected,
+			NewHash:  e.Actual,
+			Changes:  e.Changes,
+		})
+	}
+	sort.SliceStable(diff.Modified, func(i, j int) bool { return diff.Modified[i].Path < diff.Modified[j].Path })
+	return diff
+}
@@ -0,0 +1,344 @@
+package hashutil
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultPartialSize is how much of each file FindDuplicates hashes before
+// hashing whole files.
+const DefaultPartialSize = 64 * 1024
+
+// DupesOptions controls FindDuplicates.
+type DupesOptions struct {
+	// Workers is the number of files hashed concurrently. Zero means runtime.NumCPU().
+	Workers int
+	// Filter selects the files to consider below every root.
+	Filter *Filter
+	// PartialSize is how many leading bytes are hashed to split files of the
+	// same size before they are hashed in full. Zero means DefaultPartialSize.
+	PartialSize int64
+	// Empty also groups empty files, which are otherwise skipped.
+	Empty bool
+}
+
+// DupeGroup is a set of files with identical content.
+type DupeGroup struct {
+	Size     int64    `json:"size"`
+	HashType string   `json:"hash_type"`
+	Hash     string   `json:"hash"`
+	Files    []string `json:"files"`
+	// Reclaimable is the space freed by keeping only the first file. Files
+	// that are already hard links of each other are only counted once.
+	Reclaimable int64 `json:"reclaimable"`
+
+	entries []*dupeFile
+}
+
+// DupesReport lists the duplicate groups found by FindDuplicates, largest
+// reclaimable space first.
+type DupesReport struct {
+	// Interrupted is set when the search was cancelled; groups are incomplete.
+	Interrupted bool        `json:"interrupted,omitempty"`
+	Scanned     int         `json:"scanned"`
+	Groups      []DupeGroup `json:"groups"`
+	Reclaimable int64       `json:"reclaimable"`
+}
+
+type dupeFile struct {
+	path  string
+	size  int64
+	mtime time.Time
+	// id identifies the inode; empty where the platform does not expose one.
+	id            string
+	partial, full string
+	failed        bool
+}
+
+// FindDuplicates walks roots and groups files with identical content.
+func FindDuplicates(roots []string, hashType string, opts DupesOptions, onError func(string, error)) (DupesReport, error) {
+	return FindDuplicatesContext(context.Background(), roots, hashType, opts, onError)
+}
+
+// FindDuplicatesContext is like FindDuplicates but can be cancelled through ctx.
+// Files are grouped by size first, then by a hash of their first
+// opts.PartialSize bytes and finally by a hash of the whole file, so only
+// files that could still be duplicates are read in full. Files that cannot
+// be read are reported to onError and left out.
+func FindDuplicatesContext(ctx context.Context, roots []string, hashType string, opts DupesOptions, onError func(string, error)) (DupesReport, error) {
+	report := DupesReport{Groups: []DupeGroup{}}
+	if _, err := GetHasher(hashType); err != nil {
+		return report, err
+	}
+	if opts.PartialSize <= 0 {
+		opts.PartialSize = DefaultPartialSize
+	}
+	if onError == nil {
+		onError = func(string, error) {}
+	}
+
+	files, err := listDupeCandidates(ctx, roots, opts, onError)
+	report.Scanned = len(files)
+	if err != nil {
+		return finishDupes(ctx, report, err)
+	}
+
+	bySize := groupFiles(files, func(f *dupeFile) string { return strconv.FormatInt(f.size, 10) })
+	if err := hashDupeFiles(ctx, bySize, opts.Workers, onError, func(f *dupeFile) error {
+		var err error
+		f.partial, err = hashPrefix(ctx, f.path, hashType, opts.PartialSize)
+		if f.size <= opts.PartialSize {
+			f.full = f.partial
+		}
+		return err
+	}); err != nil {
+		return finishDupes(ctx, report, err)
+	}
+
+	byPartial := groupFiles(bySize, func(f *dupeFile) string { return strconv.FormatInt(f.size, 10) + ":" + f.partial })
+	if err := hashDupeFiles(ctx, byPartial, opts.Workers, onError, func(f *dupeFile) error {
+		if f.full != "" {
+			return nil
+		}
+		var err error
+		f.full, err = hashPrefix(ctx, f.path, hashType, -1)
+		return err
+	}); err != nil {
+		return finishDupes(ctx, report, err)
+	}
+
+	groups := make(map[string]*DupeGroup)
+	var keys []string
+	fullKey := func(f *dupeFile) string { return strconv.FormatInt(f.size, 10) + ":" + f.full }
+	for _, f := range groupFiles(byPartial, fullKey) {
+		key := fullKey(f)
+		g, ok := groups[key]
+		if !ok {
+			g = &DupeGroup{Size: f.size, HashType: hashType, Hash: f.full}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.entries = append(g.entries, f)
+	}
+	for _, key := range keys {
+		g := groups[key]
+		sort.Slice(g.entries, func(i, j int) bool { return g.entries[i].path < g.entries[j].path })
+		for _, f := range g.entries {
+			g.Files = append(g.Files, f.path)
+		}
+		g.Reclaimable = g.Size * int64(distinctInodes(g.entries)-1)
+		report.Reclaimable += g.Reclaimable
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Reclaimable != report.Groups[j].Reclaimable {
+			return report.Groups[i].Reclaimable > report.Groups[j].Reclaimable
+		}
+		return report.Groups[i].Files[0] < report.Groups[j].Files[0]
+	})
+	return finishDupes(ctx, report, nil)
+}
+
+func finishDupes(ctx context.Context, report DupesReport, err error) (DupesReport, error) {
+	if err != nil && ctx.Err() != nil {
+		report.Interrupted = true
+		return report, err
+	}
+	if err != nil {
+		return DupesReport{Groups: []DupeGroup{}}, err
+	}
+	return report, nil
+}
+
+// listDupeCandidates returns every regular file below roots that passes the
+// filter. A file reached through several roots is listed once.
+func listDupeCandidates(ctx context.Context, roots []string, opts DupesOptions, onError func(string, error)) ([]*dupeFile, error) {
+	var files []*dupeFile
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		filter, err := newPathFilter(root, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		err = walkFiles(ctx, root, filter, DirOptions{}, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				onError(path, err)
+				return nil
+			}
+			// Symlinks and special files are never merged or deleted.
+			if !info.Mode().IsRegular() || (info.Size() == 0 && !opts.Empty) {
+				return nil
+			}
+			key := path
+			if abs, err := filepath.Abs(path); err == nil {
+				key = abs
+			}
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+			f := &dupeFile{path: path, size: info.Size(), mtime: info.ModTime()}
+			if sys, ok := sysStat(info); ok {
+				f.id = fmt.Sprintf("%d:%d", sys.Dev, sys.Inode)
+			}
+			files = append(files, f)
+			return nil
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// groupFiles returns the files that share their key with a file of another
+// inode, i.e. those that can still be duplicates. Failed files are dropped.
+func groupFiles(files []*dupeFile, key func(*dupeFile) string) []*dupeFile {
+	groups := make(map[string][]*dupeFile)
+	var order []string
+	for _, f := range files {
+		if f.failed {
+			continue
+		}
+		k := key(f)
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], f)
+	}
+	var candidates []*dupeFile
+	for _, k := range order {
+		if distinctInodes(groups[k]) > 1 {
+			candidates = append(candidates, groups[k]...)
+		}
+	}
+	return candidates
+}
+
+// distinctInodes counts the files of group that do not share an inode.
+func distinctInodes(group []*dupeFile) int {
+	ids := make(map[string]bool)
+	n := 0
+	for _, f := range group {
+		if f.id == "" {
+			n++
+		} else if !ids[f.id] {
+			ids[f.id] = true
+			n++
+		}
+	}
+	return n
+}
+
+// hashDupeFiles runs hash for every file on a pool of workers. Files whose hash
+// fails are reported to onError and marked failed.
+func hashDupeFiles(ctx context.Context, files []*dupeFile, workers int, onError func(string, error), hash func(*dupeFile) error) error {
+	jobs := make(chan *dupeFile)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < (DirOptions{Workers: workers}).workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if err := hash(f); err != nil {
+					f.failed = true
+					if ctx.Err() == nil {
+						mu.Lock()
+						onError(f.path, err)
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// hashPrefix hashes the first n bytes of path with hashType, or all of it
+// when n is negative.
+func hashPrefix(ctx context.Context, path, hashType string, n int64) (string, error) {
+	h, err := GetHasher(hashType)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var r io.Reader = &contextReader{ctx: ctx, r: file}
+	if n >= 0 {
+		r = io.LimitReader(r, n)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LinkDuplicates replaces every file of g except the first with a hard link to
+// the first one. Files that changed since they were scanned, or that already
+// are links of the first file, are left alone. It returns the paths replaced
+// and reports each file that could not be linked to onError.
+func LinkDuplicates(g DupeGroup, onError func(string, error)) []string {
+	if len(g.entries) < 2 {
+		return nil
+	}
+	keep := g.entries[0]
+	var linked []string
+	for _, f := range g.entries[1:] {
+		if keep.id != "" && f.id == keep.id {
+			continue
+		}
+		if err := replaceWithLink(keep.path, f); err != nil {
+			if onError != nil {
+				onError(f.path, err)
+			}
+			continue
+		}
+		linked = append(linked, f.path)
+	}
+	return linked
+}
+
+func replaceWithLink(keep string, f *dupeFile) error {
+	info, err := os.Lstat(f.path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Size() != f.size || !info.ModTime().Equal(f.mtime) {
+		return errors.New("file berubah sejak dipindai, dilewati")
+	}
+	// Link next to the duplicate and rename over it, so it is never missing.
+	tmp := filepath.Join(filepath.Dir(f.path), "."+filepath.Base(f.path)+".catmint-link")
+	os.Remove(tmp)
+	if err := os.Link(keep, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package hashutil
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the gitignore-style file honoured at the root of a hashed directory.
+const IgnoreFileName = ".catmintignore"
+
+// Filter selects which files of a directory are hashed. Patterns are matched
+// against root-relative paths with forward slashes; "**" matches any number of
+// directories and a pattern without "/" matches the base name at any depth.
+type Filter struct {
+	// Include, when non-empty, keeps only files matching at least one pattern.
+	Include []string
+	// Exclude skips matching files, and whole directories when a directory matches.
+	Exclude []string
+
+	// IgnoreFile is a gitignore-style file. When empty, <root>/.catmintignore is
+	// used if it exists, unless NoIgnoreFile is set.
+	IgnoreFile   string
+	NoIgnoreFile bool
+
+	// MaxDepth limits how deep files are collected; 1 means only files directly
+	// in the root. Zero means unlimited.
+	MaxDepth int
+	// SkipHidden skips files and directories whose name starts with a dot.
+	SkipHidden bool
+	// MinSize and MaxSize bound the file size in bytes; MaxSize 0 means unlimited.
+	MinSize int64
+	MaxSize int64
+}
+
+// pathFilter is a Filter bound to a root with its ignore file loaded.
+type pathFilter struct {
+	filter Filter
+	ignore []ignoreRule
+}
+
+func newPathFilter(root string, f *Filter) (*pathFilter, error) {
+	pf := &pathFilter{}
+	if f != nil {
+		pf.filter = *f
+	}
+	if pf.filter.NoIgnoreFile {
+		return pf, nil
+	}
+
+	ignoreFile := pf.filter.IgnoreFile
+	explicit := ignoreFile != ""
+	if !explicit {
+		ignoreFile = filepath.Join(root, IgnoreFileName)
+	}
+	rules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return pf, nil
+		}
+		return nil, err
+	}
+	pf.ignore = rules
+	return pf, nil
+}
+
+// skipDir reports whether the directory rel (not the root) should be pruned.
+func (pf *pathFilter) skipDir(rel string) bool {
+	if pf.filter.MaxDepth > 0 && depth(rel) >= pf.filter.MaxDepth {
+		return true
+	}
+	if pf.filter.SkipHidden && isHidden(path.Base(rel)) {
+		return true
+	}
+	if matchAny(pf.filter.Exclude, rel) {
+		return true
+	}
+	return pf.ignored(rel, true)
+}
+
+// skipFile reports whether the file rel should not be hashed.
+func (pf *pathFilter) skipFile(rel string, info os.FileInfo) bool {
+	if pf.skipName(rel) {
+		return true
+	}
+	if info != nil && info.Mode().IsRegular() {
+		if info.Size() < pf.filter.MinSize {
+			return true
+		}
+		if pf.filter.MaxSize > 0 && info.Size() > pf.filter.MaxSize {
+			return true
+		}
+	}
+	return false
+}
+
+func (pf *pathFilter) skipName(rel string) bool {
+	if pf.filter.MaxDepth > 0 && depth(rel) > pf.filter.MaxDepth {
+		return true
+	}
+	if pf.filter.SkipHidden && isHidden(path.Base(rel)) {
+		return true
+	}
+	if len(pf.filter.Include) > 0 && !matchAny(pf.filter.Include, rel) {
+		return true
+	}
+	if matchAny(pf.filter.Exclude, rel) {
+		return true
+	}
+	return pf.ignored(rel, false)
+}
+
+// excludes reports whether a reference entry would have been filtered out of
+// the walk, checking its parent directories as well. Size limits are not
+// applied since the entry may no longer exist.
+func (pf *pathFilter) excludes(rel string) bool {
+	rel = NormalizePath(rel)
+	dir := path.Dir(rel)
+	var parents []string
+	for dir != "." && dir != "/" && dir != "" {
+		parents = append(parents, dir)
+		dir = path.Dir(dir)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if pf.skipDir(parents[i]) {
+			return true
+		}
+	}
+	return pf.skipName(rel)
+}
+
+func (pf *pathFilter) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range pf.ignore {
+		if rule.match(rel, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func depth(rel string) int {
+	return strings.Count(rel, "/") + 1
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if MatchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob matches a root-relative slash path against pattern. "**" matches
+// zero or more path segments and a pattern without "/" matches the base name
+// at any depth.
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(pattern)), "./")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(strings.TrimSuffix(pattern, "/"), "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(NormalizePath(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+type ignoreRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// loadIgnoreRules reads a gitignore-style file: "#" comments, "!" negation,
+// a trailing "/" for directories only, and patterns containing "/" anchored
+// to the root while others match at any depth.
+func loadIgnoreRules(file string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // escaped leading "#" or "!"
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		if !anchored {
+			line = "**/" + line
+		}
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
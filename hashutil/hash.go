@@ -1,29 +1,172 @@
 package hashutil
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/zeebo/blake3"
 	"golang.org/x/crypto/sha3"
 )
 
+// ErrHashMismatch is returned (wrapped) by VerifyFileHash when the digest differs.
+var ErrHashMismatch = errors.New("hash does not match")
+
+// ErrKeyRequired is returned (wrapped) for a keyed algorithm when no key was passed.
+var ErrKeyRequired = errors.New("keyed algorithm requires a key")
+
 type HashResult struct {
 	FilePath string `json:"file_path"`
 	HashType string `json:"hash_type"`
 	Hash     string `json:"hash"`
+	// Digests holds every digest when more than one algorithm was requested.
+	// HashType and Hash always mirror the first entry.
+	Digests []Digest `json:"digests,omitempty"`
+	// Meta is set when metadata collection was requested.
+	Meta *FileMeta `json:"meta,omitempty"`
+}
+
+// Digest is a single algorithm/value pair of a HashResult.
+type Digest struct {
+	HashType string `json:"hash_type"`
+	Hash     string `json:"hash"`
+}
+
+// AllDigests returns every digest of the result, including the primary one.
+func (r HashResult) AllDigests() []Digest {
+	if len(r.Digests) > 0 {
+		return r.Digests
+	}
+	return []Digest{{HashType: r.HashType, Hash: r.Hash}}
+}
+
+// Digest returns the digest computed with hashType, if the result carries one.
+func (r HashResult) Digest(hashType string) (string, bool) {
+	for _, d := range r.AllDigests() {
+		if strings.EqualFold(d.HashType, hashType) {
+			return d.Hash, true
+		}
+	}
+	return "", false
+}
+
+// ParseAlgorithms splits a comma separated algorithm list such as "sha256,md5",
+// validates every entry and removes duplicates while keeping the order. Keyed
+// algorithms are accepted without a key; see CheckKey.
+func ParseAlgorithms(spec string) ([]string, error) {
+	var algs []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		alg := strings.ToLower(strings.TrimSpace(part))
+		if alg == "" {
+			continue
+		}
+		if err := checkAlgorithm(alg); err != nil {
+			return nil, err
+		}
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	if len(algs) == 0 {
+		return nil, fmt.Errorf("unsupported hash type: %s", spec)
+	}
+	return algs, nil
+}
+
+// CheckKey reports whether every algorithm of the comma separated hashType can
+// be used with key: keyed algorithms need one, blake3-keyed exactly 32 bytes.
+func CheckKey(hashType string, key []byte) error {
+	algs, err := ParseAlgorithms(hashType)
+	if err != nil {
+		return err
+	}
+	for _, alg := range algs {
+		if _, err := GetKeyedHasher(alg, key); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// LoadHMACKey reads a key file. Its bytes are used as is, including any
+// trailing newline.
+func LoadHMACKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s: key file is empty", path)
+	}
+	return key, nil
+}
+
+// IsKeyedAlgorithm reports whether hashType needs a key.
+func IsKeyedAlgorithm(hashType string) bool {
+	name := strings.ToLower(strings.TrimSpace(hashType))
+	return strings.HasPrefix(name, "hmac-") || name == "blake3-keyed"
+}
+
+// GetHasher returns a new hash for hashType. Besides the plain algorithms it
+// accepts hmac-<alg> for every one of them and blake3-keyed (BLAKE3's native
+// keyed mode, which needs a 32-byte key, compatible with b3sum --keyed).
+// Keyed algorithms need a key and fail with ErrKeyRequired; see GetKeyedHasher.
 func GetHasher(hashType string) (hash.Hash, error) {
+	return GetKeyedHasher(hashType, nil)
+}
+
+// GetKeyedHasher is like GetHasher but keyed algorithms use key.
+func GetKeyedHasher(hashType string, key []byte) (hash.Hash, error) {
+	if err := checkAlgorithm(hashType); err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(hashType)
+	if !IsKeyedAlgorithm(name) {
+		return newHasher(name)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s: %w", hashType, ErrKeyRequired)
+	}
+	if name == "blake3-keyed" {
+		h, err := blake3.NewKeyed(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s requires a 32-byte key, got %d bytes", hashType, len(key))
+		}
+		return h, nil
+	}
+	base := strings.TrimPrefix(name, "hmac-")
+	return hmac.New(func() hash.Hash {
+		h, _ := newHasher(base)
+		return h
+	}, key), nil
+}
+
+// checkAlgorithm reports whether hashType names a supported algorithm.
+func checkAlgorithm(hashType string) error {
+	name := strings.ToLower(hashType)
+	if name == "blake3-keyed" {
+		return nil
+	}
+	if _, err := newHasher(strings.TrimPrefix(name, "hmac-")); err != nil {
+		return fmt.Errorf("unsupported hash type: %s", hashType)
+	}
+	return nil
+}
+
+func newHasher(hashType string) (hash.Hash, error) {
 	switch strings.ToLower(hashType) {
 	case "sha256":
 		return sha256.New(), nil
@@ -42,117 +185,104 @@ func GetHasher(hashType string) (hash.Hash, error) {
 	}
 }
 
+// GenerateFileHash hashes filePath. hashType may list several algorithms
+// (e.g. "sha256,md5"); all digests are computed from a single read of the file.
 func GenerateFileHash(filePath, hashType string) (HashResult, error) {
+	return GenerateFileHashContext(context.Background(), filePath, hashType)
+}
+
+// GenerateFileHashContext is like GenerateFileHash but stops reading as soon as ctx is done.
+func GenerateFileHashContext(ctx context.Context, filePath, hashType string) (HashResult, error) {
+	return GenerateKeyedFileHash(ctx, filePath, hashType, nil)
+}
+
+// GenerateKeyedFileHash is like GenerateFileHashContext but keyed algorithms use key.
+func GenerateKeyedFileHash(ctx context.Context, filePath, hashType string, key []byte) (HashResult, error) {
+	if err := ctx.Err(); err != nil {
+		return HashResult{}, err
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return HashResult{}, err
 	}
 	defer file.Close()
 
-	hasher, err := GetHasher(hashType)
+	algs, err := ParseAlgorithms(hashType)
 	if err != nil {
 		return HashResult{}, err
 	}
 
-	if _, err := io.Copy(hasher, file); err != nil {
+	// Every hasher is fed from the same read so the file is only read once.
+	hashers := make([]hash.Hash, len(algs))
+	writers := make([]io.Writer, len(algs))
+	for i, alg := range algs {
+		if hashers[i], err = GetKeyedHasher(alg, key); err != nil {
+			return HashResult{}, err
+		}
+		writers[i] = hashers[i]
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), &contextReader{ctx: ctx, r: file}); err != nil {
 		return HashResult{}, err
 	}
 
-	hashString := hex.EncodeToString(hasher.Sum(nil))
-	return HashResult{
-		FilePath: filePath,
-		HashType: strings.ToUpper(hashType),
-		Hash:     hashString,
-	}, nil
+	result := HashResult{FilePath: filePath}
+	for i, alg := range algs {
+		result.Digests = append(result.Digests, Digest{
+			HashType: strings.ToUpper(alg),
+			Hash:     hex.EncodeToString(hashers[i].Sum(nil)),
+		})
+	}
+	result.HashType = result.Digests[0].HashType
+	result.Hash = result.Digests[0].Hash
+	if len(result.Digests) == 1 {
+		result.Digests = nil
+	}
+	return result, nil
 }
 
-func GenerateDirHash(dirPath, hashType string, onResult func(HashResult), onError func(string, error)) ([]HashResult, error) {
-	var results []HashResult
-
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if onError != nil {
-				onError(path, err)
-			}
-			return nil
-		}
-		if info.IsDir() {
-			return nil
-		}
-
-		result, err := GenerateFileHash(path, hashType)
-		if err != nil {
-			if onError != nil {
-				onError(path, err)
-			}
-			return nil
-		}
-
-		if onResult != nil {
-			onResult(result)
-		}
-		results = append(results, result)
-		return nil
-	})
-	return results, err
+// contextReader fails the next Read once ctx is done, so a large copy can be interrupted.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
 }
 
-func VerifyFileHash(filePath, hashType, expectedHash string) error {
-	result, err := GenerateFileHash(filePath, hashType)
-	if err != nil {
-		return err
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
 	}
-	if !strings.EqualFold(result.Hash, expectedHash) {
-		return fmt.Errorf("hash does not match. Expected: %s, Got: %s", expectedHash, result.Hash)
-	}
-	return nil
+	return c.r.Read(p)
 }
 
-// CompareResults membandingkan hash hasil saat ini dengan referensi.
-func CompareResults(actual, reference []HashResult) {
-	referenceMap := make(map[string]HashResult)
-	for _, ref := range reference {
-		// Normalisasi path untuk jaga-jaga
-		referenceMap[strings.TrimSpace(ref.FilePath)] = ref
-	}
-
-	matchCount := 0
-	mismatchCount := 0
-	notFoundCount := 0
-
-	var mismatchFiles []string
-	var notFoundFiles []string
+// HashEqual compares two hex digests case-insensitively in constant time, so
+// keyed digests cannot be guessed byte by byte from timing.
+func HashEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(a)), []byte(strings.ToLower(b))) == 1
+}
 
-	for _, a := range actual {
-		ref, found := referenceMap[strings.TrimSpace(a.FilePath)]
-		if !found {
-			notFoundFiles = append(notFoundFiles, a.FilePath)
-			notFoundCount++
-			continue
-		}
+// GenerateDirHash hashes every regular file below dirPath with the default number of workers.
+func GenerateDirHash(dirPath, hashType string, onResult func(HashResult), onError func(string, error)) ([]HashResult, error) {
+	return GenerateDirHashWithOptions(dirPath, hashType, DirOptions{}, onResult, onError)
+}
 
-		// Bandingkan hash dengan ignore case
-		if strings.EqualFold(a.Hash, ref.Hash) {
-			matchCount++
-		} else {
-			mismatchFiles = append(mismatchFiles, a.FilePath)
-			mismatchCount++
-		}
-	}
+func VerifyFileHash(filePath, hashType, expectedHash string) error {
+	return VerifyFileHashContext(context.Background(), filePath, hashType, expectedHash)
+}
 
-	fmt.Printf("Summary: %d match, %d mismatch, %d not found\n", matchCount, mismatchCount, notFoundCount)
+// VerifyFileHashContext is like VerifyFileHash but can be cancelled through ctx.
+func VerifyFileHashContext(ctx context.Context, filePath, hashType, expectedHash string) error {
+	return VerifyKeyedFileHash(ctx, filePath, hashType, expectedHash, nil)
+}
 
-	if mismatchCount > 0 {
-		fmt.Println("\n❌ Mismatch:")
-		for _, path := range mismatchFiles {
-			fmt.Printf("- %s\n", path)
-		}
+// VerifyKeyedFileHash is like VerifyFileHashContext but keyed algorithms use key.
+func VerifyKeyedFileHash(ctx context.Context, filePath, hashType, expectedHash string, key []byte) error {
+	result, err := GenerateKeyedFileHash(ctx, filePath, hashType, key)
+	if err != nil {
+		return err
 	}
-
-	if notFoundCount > 0 {
-		fmt.Println("\n❌ Not found in reference:")
-		for _, path := range notFoundFiles {
-			fmt.Printf("- %s\n", path)
-		}
+	if !HashEqual(result.Hash, expectedHash) {
+		return fmt.Errorf("%w. Expected: %s, Got: %s", ErrHashMismatch, expectedHash, result.Hash)
 	}
+	return nil
 }
@@ -0,0 +1,222 @@
+package hashutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ManifestVersion is the version of the manifest document written by catmint.
+const ManifestVersion = 1
+
+// manifestCommentKey starts the comment header of txt/csv/tsv manifests:
+//
+//	# catmint-manifest: 1
+//	# tool: catmint v1.1.1
+//	# root: /srv/data
+//	...
+const manifestCommentKey = "catmint-manifest"
+
+// ManifestHeader records where and how a manifest was produced.
+type ManifestHeader struct {
+	Tool    string `json:"tool"`
+	Created string `json:"created,omitempty"`
+	Host    string `json:"host,omitempty"`
+	Root    string `json:"root,omitempty"`
+	// WalkRoot is the root as given on the command line when it differs from
+	// Root. Entries that are not relative start with it.
+	WalkRoot   string `json:"walk_root,omitempty"`
+	Algorithms string `json:"algorithms,omitempty"`
+	// Options holds the command line options that shaped the entries,
+	// e.g. relative, meta, policy or exclude.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Manifest is a reference file with its optional header. Header is nil for
+// bare lists written by older versions or by other tools.
+type Manifest struct {
+	Version int             `json:"manifest_version"`
+	Header  *ManifestHeader `json:"header,omitempty"`
+	Entries []HashResult    `json:"entries"`
+}
+
+// LoadManifest is like LoadHashReference but also returns the header.
+func LoadManifest(path string) (Manifest, error) {
+	if !isReferenceFileName(path) {
+		return Manifest{}, fmt.Errorf("format referensi tidak didukung: %s", filepath.Ext(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if isSignedManifest(data) {
+		return Manifest{}, ErrSignedManifest
+	}
+	return ParseManifest(data, path)
+}
+
+// ParseManifest parses reference content read from a file called name,
+// including a JSON document or comment header if there is one.
+func ParseManifest(data []byte, name string) (Manifest, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	var m Manifest
+	var err error
+	switch ext {
+	case ".json":
+		return parseJSONManifest(data)
+	case ".ndjson", ".jsonl":
+		return parseNDJSON(data)
+	case ".csv", ".tsv":
+		comma := ','
+		if ext == ".tsv" {
+			comma = '\t'
+		}
+		m.Header, data = parseCommentHeader(data)
+		m.Entries, err = parseCSV(data, comma)
+	case ".txt":
+		m.Header, _ = parseCommentHeader(data)
+		m.Entries, err = parseTXT(data, name)
+	default:
+		if !isChecksumFileName(name) {
+			return Manifest{}, fmt.Errorf("format referensi tidak didukung: %s", ext)
+		}
+		m.Header, _ = parseCommentHeader(data)
+		m.Entries, err = parseTXT(data, name)
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	if m.Header != nil {
+		m.Version = ManifestVersion
+	}
+	return m, nil
+}
+
+func parseJSONManifest(data []byte) (Manifest, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		var m Manifest
+		if err := json.Unmarshal(data, &m.Entries); err != nil {
+			return Manifest{}, err
+		}
+		return m, nil
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, err
+	}
+	if err := checkManifestVersion(m.Version); err != nil {
+		return Manifest{}, err
+	}
+	return m, nil
+}
+
+func checkManifestVersion(v int) error {
+	if v < 1 {
+		return errors.New("dokumen manifest tanpa manifest_version")
+	}
+	if v > ManifestVersion {
+		return fmt.Errorf("manifest_version %d tidak didukung (maksimal %d)", v, ManifestVersion)
+	}
+	return nil
+}
+
+// EntryRoot returns the prefix the entry paths start with, which makes them
+// relative to the hashed root once stripped. It is empty when the entries
+// already are relative (-relative or -merkle).
+func (h *ManifestHeader) EntryRoot() string {
+	if h == nil || h.Options["relative"] == "true" || h.Options["merkle"] == "true" {
+		return ""
+	}
+	if h.WalkRoot != "" {
+		return h.WalkRoot
+	}
+	return h.Root
+}
+
+// CommentLines returns the header as "# key: value" lines for txt, csv and tsv
+// manifests. Options are written as "# option.<name>: <value>" in name order.
+func (h *ManifestHeader) CommentLines() []string {
+	lines := []string{fmt.Sprintf("# %s: %d", manifestCommentKey, ManifestVersion)}
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("# %s: %s", key, value))
+		}
+	}
+	add("tool", h.Tool)
+	add("created", h.Created)
+	add("host", h.Host)
+	add("root", h.Root)
+	add("walk_root", h.WalkRoot)
+	add("algorithms", h.Algorithms)
+	names := make([]string, 0, len(h.Options))
+	for name := range h.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("option."+name, h.Options[name])
+	}
+	return lines
+}
+
+// parseCommentHeader reads the leading "# key: value" lines of data. It
+// returns nil when data does not start with a catmint comment header, and the
+// data after the comment lines either way.
+func parseCommentHeader(data []byte) (*ManifestHeader, []byte) {
+	var h *ManifestHeader
+	rest := data
+	for len(rest) > 0 && rest[0] == '#' {
+		line := rest
+		next := []byte(nil)
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, next = rest[:i], rest[i+1:]
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(string(line), "#")), ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if h == nil {
+			// Other comments at the top are not ours; leave the data as is.
+			if !ok || key != manifestCommentKey {
+				return nil, data
+			}
+			if v, err := strconv.Atoi(value); err != nil || checkManifestVersion(v) != nil {
+				return nil, data
+			}
+			h = &ManifestHeader{}
+		} else if ok {
+			h.set(key, value)
+		}
+		rest = next
+	}
+	return h, rest
+}
+
+func (h *ManifestHeader) set(key, value string) {
+	switch key {
+	case "tool":
+		h.Tool = value
+	case "created":
+		h.Created = value
+	case "host":
+		h.Host = value
+	case "root":
+		h.Root = value
+	case "walk_root":
+		h.WalkRoot = value
+	case "algorithms":
+		h.Algorithms = value
+	default:
+		if name, ok := strings.CutPrefix(key, "option."); ok {
+			if h.Options == nil {
+				h.Options = make(map[string]string)
+			}
+			h.Options[name] = value
+		}
+	}
+}
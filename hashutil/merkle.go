@@ -0,0 +1,229 @@
+package hashutil
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MerkleOptions controls what goes into a Merkle tree besides file content and names.
+type MerkleOptions struct {
+	// Modes includes each file's permission string (from HashResult.Meta), so a
+	// chmod changes the root digest.
+	Modes bool
+	// Key is the key of a keyed hashType.
+	Key []byte
+}
+
+// MerkleNode is a file or directory of a Merkle tree. Directory hashes cover the
+// names, kinds, optional modes and hashes of their children, sorted by name.
+type MerkleNode struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Dir      bool          `json:"dir,omitempty"`
+	Mode     string        `json:"mode,omitempty"`
+	Hash     string        `json:"hash"`
+	Children []*MerkleNode `json:"children,omitempty"`
+}
+
+// MerkleChange is a file or directory whose hash differs between two trees.
+type MerkleChange struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"dir,omitempty"`
+	// Change is "modified", "added" or "removed".
+	Change string `json:"change"`
+}
+
+const (
+	merkleLeafPrefix = 0x00
+	merkleDirPrefix  = 0x01
+)
+
+// BuildMerkleTree builds a deterministic Merkle tree over results, which must
+// carry root-relative paths (hash -relative) and a hashType digest. Leaves hash
+// the content digest and directories hash their sorted entries, so the root
+// digest pins the whole tree. Empty directories are not part of the tree.
+func BuildMerkleTree(results []HashResult, hashType string, opts MerkleOptions) (*MerkleNode, error) {
+	if _, err := GetKeyedHasher(hashType, opts.Key); err != nil {
+		return nil, err
+	}
+	root := &MerkleNode{Name: ".", Path: ".", Dir: true}
+	for _, r := range results {
+		rel := NormalizePath(r.FilePath)
+		if path.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("merkle tree needs root-relative paths, got %s", r.FilePath)
+		}
+		digest, ok := r.Digest(hashType)
+		if !ok {
+			return nil, fmt.Errorf("%s: no %s digest", r.FilePath, strings.ToUpper(hashType))
+		}
+		content, err := hex.DecodeString(digest)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid digest: %w", r.FilePath, err)
+		}
+
+		leaf := &MerkleNode{Name: path.Base(rel), Path: rel}
+		if opts.Modes && r.Meta != nil {
+			leaf.Mode = r.Meta.Mode
+		}
+		h, _ := GetKeyedHasher(hashType, opts.Key)
+		h.Write([]byte{merkleLeafPrefix})
+		h.Write(content)
+		leaf.Hash = hex.EncodeToString(h.Sum(nil))
+
+		if err := root.insert(strings.Split(rel, "/"), leaf); err != nil {
+			return nil, err
+		}
+	}
+	if err := root.seal(hashType, opts.Key); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (n *MerkleNode) insert(parts []string, leaf *MerkleNode) error {
+	child := n.child(parts[0])
+	if len(parts) == 1 {
+		if child != nil {
+			return fmt.Errorf("merkle tree: duplicate entry %s", leaf.Path)
+		}
+		n.Children = append(n.Children, leaf)
+		return nil
+	}
+	if child == nil {
+		child = &MerkleNode{Name: parts[0], Path: path.Join(n.childPrefix(), parts[0]), Dir: true}
+		n.Children = append(n.Children, child)
+	} else if !child.Dir {
+		return fmt.Errorf("merkle tree: %s is both a file and a directory", child.Path)
+	}
+	return child.insert(parts[1:], leaf)
+}
+
+func (n *MerkleNode) childPrefix() string {
+	if n.Path == "." {
+		return ""
+	}
+	return n.Path
+}
+
+func (n *MerkleNode) child(name string) *MerkleNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// seal sorts the children and computes directory hashes bottom-up.
+func (n *MerkleNode) seal(hashType string, key []byte) error {
+	if !n.Dir {
+		return nil
+	}
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	for _, c := range n.Children {
+		if err := c.seal(hashType, key); err != nil {
+			return err
+		}
+	}
+	hash, err := merkleDirHash(n.Children, hashType, key)
+	if err != nil {
+		return err
+	}
+	n.Hash = hash
+	return nil
+}
+
+// merkleDirHash hashes the sorted entries of a directory:
+// 0x01 || for each entry: kind || len(name) || name || len(mode) || mode || len(hash) || hash.
+func merkleDirHash(entries []*MerkleNode, hashType string, key []byte) (string, error) {
+	h, err := GetKeyedHasher(hashType, key)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte{merkleDirPrefix})
+	for _, e := range entries {
+		sum, err := hex.DecodeString(e.Hash)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid hash: %w", e.Path, err)
+		}
+		kind := byte(merkleLeafPrefix)
+		if e.Dir {
+			kind = merkleDirPrefix
+		}
+		h.Write([]byte{kind})
+		writeMerkleField(h, []byte(e.Name))
+		writeMerkleField(h, []byte(e.Mode))
+		writeMerkleField(h, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeMerkleField(h hash.Hash, field []byte) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(field)))])
+	h.Write(field)
+}
+
+// Find returns the node at the root-relative path rel, or nil.
+func (n *MerkleNode) Find(rel string) *MerkleNode {
+	rel = NormalizePath(rel)
+	if rel == "." || rel == "" {
+		return n
+	}
+	node := n
+	for _, part := range strings.Split(rel, "/") {
+		if node = node.child(part); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// DiffMerkleTrees descends both trees from the root and returns every
+// directory and file whose hash differs, parents before children. Subtrees
+// with equal hashes are skipped without being visited.
+func DiffMerkleTrees(expected, actual *MerkleNode) []MerkleChange {
+	var changes []MerkleChange
+	diffMerkleNode(expected, actual, &changes)
+	return changes
+}
+
+func diffMerkleNode(expected, actual *MerkleNode, changes *[]MerkleChange) {
+	if expected.Hash == actual.Hash && expected.Mode == actual.Mode && expected.Dir == actual.Dir {
+		return
+	}
+	*changes = append(*changes, MerkleChange{Path: actual.Path, Dir: expected.Dir && actual.Dir, Change: "modified"})
+	if !expected.Dir || !actual.Dir {
+		return
+	}
+
+	names := make(map[string]bool)
+	for _, c := range expected.Children {
+		names[c.Name] = true
+	}
+	for _, c := range actual.Children {
+		names[c.Name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		e, a := expected.child(name), actual.child(name)
+		switch {
+		case e == nil:
+			*changes = append(*changes, MerkleChange{Path: a.Path, Dir: a.Dir, Change: "added"})
+		case a == nil:
+			*changes = append(*changes, MerkleChange{Path: e.Path, Dir: e.Dir, Change: "removed"})
+		default:
+			diffMerkleNode(e, a, changes)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package hashutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileMeta is the optional file metadata recorded next to the digests.
+type FileMeta struct {
+	Size       int64  `json:"size"`
+	Mode       string `json:"mode"`
+	UID        uint32 `json:"uid"`
+	GID        uint32 `json:"gid"`
+	ModTime    string `json:"mtime"`
+	Inode      uint64 `json:"inode,omitempty"`
+	Nlink      uint64 `json:"nlink,omitempty"`
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
+// FileMetadata returns the metadata of path without following a final symlink.
+func FileMetadata(path string) (*FileMeta, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	return newFileMeta(path, info), nil
+}
+
+func newFileMeta(path string, info os.FileInfo) *FileMeta {
+	meta := &FileMeta{
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime().UTC().Format(time.RFC3339Nano),
+	}
+	if sys, ok := sysStat(info); ok {
+		meta.UID = sys.UID
+		meta.GID = sys.GID
+		meta.Inode = sys.Inode
+		meta.Nlink = sys.Nlink
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(path); err == nil {
+			meta.LinkTarget = target
+		}
+	}
+	return meta
+}
+
+// metaChanges lists the attributes selected by rules that differ between the
+// recorded and the current metadata, e.g. "mode: -rw-r--r-- -> -rwxr-xr-x".
+func metaChanges(recorded, current *FileMeta, rules RuleSet) []string {
+	if recorded == nil || current == nil {
+		return nil
+	}
+	var changes []string
+	add := func(name string, before, after any) {
+		if fmt.Sprint(before) != fmt.Sprint(after) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", name, before, after))
+		}
+	}
+	switch {
+	case rules.Growing:
+		if current.Size < recorded.Size {
+			changes = append(changes, fmt.Sprintf("size: %d -> %d (shrank)", recorded.Size, current.Size))
+		}
+	case rules.Size:
+		add("size", recorded.Size, current.Size)
+	}
+	if rules.Perms {
+		add("mode", recorded.Mode, current.Mode)
+	}
+	if rules.Owner {
+		add("uid", recorded.UID, current.UID)
+		add("gid", recorded.GID, current.GID)
+	}
+	if rules.MTime && !rules.Growing {
+		add("mtime", recorded.ModTime, current.ModTime)
+	}
+	if rules.Inode {
+		if recorded.Inode != 0 {
+			add("inode", recorded.Inode, current.Inode)
+		}
+		if recorded.Nlink != 0 {
+			add("nlink", recorded.Nlink, current.Nlink)
+		}
+	}
+	if rules.Hash || rules.Perms {
+		add("link_target", recorded.LinkTarget, current.LinkTarget)
+	}
+	return changes
+}
+
+// FormatMetaFields renders meta as space separated key=value pairs, the form
+// used after the digest in txt manifests.
+func FormatMetaFields(meta *FileMeta) string {
+	if meta == nil {
+		return ""
+	}
+	fields := []string{
+		"size=" + strconv.FormatInt(meta.Size, 10),
+		"mode=" + meta.Mode,
+		"uid=" + strconv.FormatUint(uint64(meta.UID), 10),
+		"gid=" + strconv.FormatUint(uint64(meta.GID), 10),
+		"mtime=" + meta.ModTime,
+	}
+	if meta.Inode != 0 {
+		fields = append(fields, "inode="+strconv.FormatUint(meta.Inode, 10))
+	}
+	if meta.Nlink != 0 {
+		fields = append(fields, "nlink="+strconv.FormatUint(meta.Nlink, 10))
+	}
+	if meta.LinkTarget != "" {
+		fields = append(fields, "link_target="+strconv.Quote(meta.LinkTarget))
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseMetaFields parses the key=value pairs written by FormatMetaFields.
+// It returns nil when s holds no recognised field.
+func parseMetaFields(s string) *FileMeta {
+	meta := &FileMeta{}
+	found := false
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			break
+		}
+		key, rest := s[:eq], s[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			value, _ = strconv.Unquote(quoted)
+			s = rest[len(quoted):]
+		} else if sp := strings.IndexByte(rest, ' '); sp >= 0 {
+			value, s = rest[:sp], rest[sp:]
+		} else {
+			value, s = rest, ""
+		}
+		if setMetaField(meta, key, value) {
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return meta
+}
+
+// setMetaField assigns a single attribute by its manifest name (as used in txt
+// key=value pairs and csv headers).
+func setMetaField(meta *FileMeta, key, value string) bool {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "size":
+		meta.Size, _ = strconv.ParseInt(value, 10, 64)
+	case "mode":
+		meta.Mode = value
+	case "uid":
+		n, _ := strconv.ParseUint(value, 10, 32)
+		meta.UID = uint32(n)
+	case "gid":
+		n, _ := strconv.ParseUint(value, 10, 32)
+		meta.GID = uint32(n)
+	case "mtime":
+		meta.ModTime = value
+	case "inode":
+		meta.Inode, _ = strconv.ParseUint(value, 10, 64)
+	case "nlink":
+		meta.Nlink, _ = strconv.ParseUint(value, 10, 64)
+	case "link_target":
+		meta.LinkTarget = value
+	default:
+		return false
+	}
+	return true
+}
+
+// MetaCSVHeader lists the extra csv columns written when results carry metadata.
+var MetaCSVHeader = []string{"size", "mode", "uid", "gid", "mtime", "inode", "nlink", "link_target"}
+
+// MetaCSVFields returns the csv column values matching MetaCSVHeader.
+func MetaCSVFields(meta *FileMeta) []string {
+	if meta == nil {
+		return make([]string, len(MetaCSVHeader))
+	}
+	return []string{
+		strconv.FormatInt(meta.Size, 10),
+		meta.Mode,
+		strconv.FormatUint(uint64(meta.UID), 10),
+		strconv.FormatUint(uint64(meta.GID), 10),
+		meta.ModTime,
+		strconv.FormatUint(meta.Inode, 10),
+		strconv.FormatUint(meta.Nlink, 10),
+		meta.LinkTarget,
+	}
+}
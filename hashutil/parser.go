@@ -1,6 +1,7 @@
 package hashutil
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -10,40 +11,65 @@ import (
 	"strings"
 )
 
+// LoadHashReference loads the entries of a reference file; see LoadManifest
+// for its header.
 func LoadHashReference(path string) ([]HashResult, error) {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".json":
-		return loadJSON(path)
-	case ".csv":
-		return loadCSV(path)
-	case ".txt":
-		return loadTXT(path)
-	default:
-		return nil, fmt.Errorf("format referensi tidak didukung: %s", ext)
+	m, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
 	}
+	return m.Entries, nil
 }
 
-func loadJSON(path string) ([]HashResult, error) {
-	data, err := os.ReadFile(path)
+// ParseHashReference parses reference content that was read from a file
+// called name; the name selects the format like in LoadHashReference.
+func ParseHashReference(data []byte, name string) ([]HashResult, error) {
+	m, err := ParseManifest(data, name)
 	if err != nil {
 		return nil, err
 	}
-	var result []HashResult
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, err
+	return m.Entries, nil
+}
+
+func isReferenceFileName(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".ndjson", ".jsonl", ".csv", ".tsv", ".txt":
+		return true
 	}
-	return result, nil
+	return isChecksumFileName(name)
 }
 
-func loadCSV(path string) ([]HashResult, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// parseNDJSON reads one JSON object per line, as written by -format ndjson.
+// The first line may be the manifest header instead of a result.
+func parseNDJSON(data []byte) (Manifest, error) {
+	var m Manifest
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if m.Entries == nil && m.Header == nil && bytes.Contains(line, []byte(`"manifest_version"`)) {
+			if err := json.Unmarshal(line, &m); err != nil {
+				return Manifest{}, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if err := checkManifestVersion(m.Version); err != nil {
+				return Manifest{}, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			m.Entries = nil
+			continue
+		}
+		var r HashResult
+		if err := json.Unmarshal(line, &r); err != nil {
+			return Manifest{}, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		m.Entries = append(m.Entries, r)
 	}
-	defer file.Close()
+	return m, nil
+}
 
-	reader := csv.NewReader(file)
+func parseCSV(data []byte, comma rune) ([]HashResult, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
 	rows, err := reader.ReadAll()
 	if err != nil {
 		return nil, err
@@ -54,6 +80,7 @@ func loadCSV(path string) ([]HashResult, error) {
 	}
 
 	var results []HashResult
+	header := rows[0]
 	for i, row := range rows {
 		if i == 0 {
 			continue // skip header
@@ -61,47 +88,138 @@ func loadCSV(path string) ([]HashResult, error) {
 		if len(row) < 3 {
 			continue
 		}
-		results = append(results, HashResult{
+		result := HashResult{
 			FilePath: strings.TrimSpace(row[0]),
 			HashType: strings.TrimSpace(row[1]),
 			Hash:     strings.TrimSpace(row[2]),
-		})
+		}
+		// Optional metadata columns, identified by header name
+		for col := 3; col < len(row) && col < len(header); col++ {
+			if strings.TrimSpace(row[col]) == "" {
+				continue
+			}
+			if result.Meta == nil {
+				result.Meta = &FileMeta{}
+			}
+			setMetaField(result.Meta, header[col], row[col])
+		}
+		results = append(results, result)
 	}
-	return results, nil
+	return mergeDigests(results)
 }
 
-func loadTXT(path string) ([]HashResult, error) {
+func parseTXT(data []byte, name string) ([]HashResult, error) {
+	var results []HashResult
+	hint := algorithmHint(name)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if r, ok := parseTXTLine(line, hint); ok {
+			results = append(results, r)
+		}
+	}
+	return mergeDigests(results)
+}
+
+// parseTXTLine parses one line of a txt manifest or checksum file. hint is the
+// algorithm assumed for GNU checksum lines.
+func parseTXTLine(line, hint string) (HashResult, bool) {
 	// Format:
 	// SHA256 hash of file ./path/to/file: hashvalue
+	// or coreutils style (see checksum.go):
+	// hashvalue  ./path/to/file
+	// SHA256 (./path/to/file) = hashvalue
+	parts := strings.SplitN(line, " hash of file ", 2)
+	if len(parts) != 2 {
+		return parseChecksumLine(line, hint)
+	}
+	hashType := strings.TrimSpace(parts[0])
+	rest := strings.SplitN(parts[1], ": ", 2)
+	if len(rest) != 2 {
+		return HashResult{}, false
+	}
+	filePath := strings.TrimSpace(rest[0])
+	hashVal := strings.TrimSpace(rest[1])
+
+	// Optional metadata after the digest: "<hash> size=12 mode=-rw-r--r-- ..."
+	var meta *FileMeta
+	if sp := strings.IndexByte(hashVal, ' '); sp >= 0 {
+		meta = parseMetaFields(hashVal[sp+1:])
+		hashVal = hashVal[:sp]
+	}
+	return HashResult{
+		FilePath: filePath,
+		HashType: hashType,
+		Hash:     hashVal,
+		Meta:     meta,
+	}, true
+}
+
+// LoadChecksumFile reads path for checking every line like sha256sum -c:
+// entries are returned in file order, repeated paths included, and lines that
+// are not properly formatted are counted in bad and skipped. Structured
+// formats (json, ndjson, csv, tsv) are loaded like LoadHashReference.
+func LoadChecksumFile(path string) (entries []HashResult, bad int, err error) {
+	if !isReferenceFileName(path) {
+		return nil, 0, fmt.Errorf("format referensi tidak didukung: %s", filepath.Ext(path))
+	}
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	lines := strings.Split(string(data), "\n")
-	var results []HashResult
+	if isSignedManifest(data) {
+		return nil, 0, ErrSignedManifest
+	}
+	return parseChecksumFile(data, path)
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+// parseChecksumFile parses checksum content read from a file called name, as
+// described for LoadChecksumFile.
+func parseChecksumFile(data []byte, name string) (entries []HashResult, bad int, err error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".ndjson", ".jsonl", ".csv", ".tsv":
+		entries, err = ParseHashReference(data, name)
+		return entries, 0, err
+	}
+	hint := algorithmHint(name)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, ok := parseTXTLine(line, hint)
+		if !ok {
+			bad++
 			continue
 		}
-		parts := strings.SplitN(line, " hash of file ", 2)
-		if len(parts) != 2 {
+		entries = append(entries, r)
+	}
+	return entries, bad, nil
+}
+
+// mergeDigests folds rows that describe the same file with different algorithms
+// (one line/row per digest in txt and csv) back into a single multi-digest result.
+// A repeated row is dropped, but two different digests of the same algorithm
+// for one file are an error: either one could be the expected one.
+func mergeDigests(rows []HashResult) ([]HashResult, error) {
+	var results []HashResult
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, found := index[row.FilePath]
+		if !found {
+			index[row.FilePath] = len(results)
+			results = append(results, row)
 			continue
 		}
-		hashType := strings.TrimSpace(parts[0])
-		rest := strings.SplitN(parts[1], ": ", 2)
-		if len(rest) != 2 {
+		if hash, dup := results[i].Digest(row.HashType); dup {
+			if !HashEqual(hash, row.Hash) {
+				return nil, fmt.Errorf("%s: digest %s berbeda untuk file yang sama", row.FilePath, row.HashType)
+			}
 			continue
 		}
-		filePath := strings.TrimSpace(rest[0])
-		hashVal := strings.TrimSpace(rest[1])
-
-		results = append(results, HashResult{
-			FilePath: filePath,
-			HashType: hashType,
-			Hash:     hashVal,
-		})
+		results[i].Digests = append(results[i].AllDigests(), Digest{HashType: row.HashType, Hash: row.Hash})
 	}
 	return results, nil
 }
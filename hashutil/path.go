@@ -0,0 +1,64 @@
+package hashutil
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// NormalizePath returns the form used to match manifest entries: forward
+// slashes, cleaned, without "./" or redundant dot segments.
+func NormalizePath(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// RebaseResults re-anchors reference paths on root: entries located below root
+// become relative to it, everything else is only normalized. This lets a
+// manifest recorded with raw walk paths (./myfolder/sub/a.bin or an absolute
+// path) be matched against a tree hashed with DirOptions.RelativePaths.
+func RebaseResults(results []HashResult, root string) []HashResult {
+	rebased := make([]HashResult, len(results))
+	for i, r := range results {
+		r.FilePath = rebasePath(r.FilePath, root)
+		rebased[i] = r
+	}
+	return rebased
+}
+
+func rebasePath(p, root string) string {
+	p = NormalizePath(p)
+	root = NormalizePath(root)
+	if root == "" || root == "." {
+		return p
+	}
+	if rel, ok := relativeTo(p, root); ok {
+		return rel
+	}
+
+	// One side absolute, the other relative to the working directory.
+	if filepath.IsAbs(filepath.FromSlash(p)) != filepath.IsAbs(filepath.FromSlash(root)) {
+		absPath, errPath := filepath.Abs(filepath.FromSlash(p))
+		absRoot, errRoot := filepath.Abs(filepath.FromSlash(root))
+		if errPath == nil && errRoot == nil {
+			if rel, ok := relativeTo(NormalizePath(absPath), NormalizePath(absRoot)); ok {
+				return rel
+			}
+		}
+	}
+	return p
+}
+
+func relativeTo(p, root string) (string, bool) {
+	if p == root {
+		return path.Base(p), true
+	}
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	if strings.HasPrefix(p, prefix) {
+		return strings.TrimPrefix(p, prefix), true
+	}
+	return "", false
+}
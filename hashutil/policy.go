@@ -0,0 +1,263 @@
+package hashutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultRuleName is the rule set applied to files no policy line selects.
+const DefaultRuleName = "DEFAULT"
+
+// RuleSet lists the attributes checked for the files a policy line selects.
+type RuleSet struct {
+	// Algorithm overrides the hash algorithm for these files; empty means the
+	// algorithm given to the command.
+	Algorithm string
+	Hash      bool
+	Size      bool
+	Perms     bool
+	Owner     bool
+	MTime     bool
+	Inode     bool
+	// Growing is for log files: the size may only grow and content, size
+	// and mtime changes are accepted.
+	Growing bool
+	// Ignore excludes the files from the baseline and from verification.
+	Ignore bool
+}
+
+// allAttributes is used when no policy is given: everything that was recorded is checked.
+var allAttributes = RuleSet{Hash: true, Size: true, Perms: true, Owner: true, MTime: true, Inode: true}
+
+type policyRule struct {
+	pattern string
+	rules   RuleSet
+}
+
+// Policy maps root-relative path patterns to rule sets, in the spirit of
+// AIDE/Tripwire configuration files. The last matching line wins.
+//
+//	# named rule sets
+//	NORMAL  = hash+size+perms+owner+mtime
+//	LOG     = growing+perms+owner
+//	DEFAULT = hash
+//
+//	# pattern followed by a rule set name or an inline attribute list
+//	etc/**            NORMAL
+//	etc/ssl/**        sha512+perms+owner
+//	var/log/**        LOG
+//	!var/cache/**
+//
+// Attributes: hash, size, perms, owner, mtime, inode, growing, or an
+// algorithm name (sha256, sha512, ...) which implies hash. A leading "/" on a
+// pattern anchors it to the root, which is the default anyway; a pattern
+// without "/" matches the base name at any depth.
+type Policy struct {
+	rules []policyRule
+	sets  map[string]RuleSet
+}
+
+// LoadPolicy reads a policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	policy, err := ParsePolicy(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses policy lines from r.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	p := &Policy{sets: map[string]RuleSet{DefaultRuleName: {Hash: true}}}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// NAME = attrs
+		if name, attrs, ok := strings.Cut(line, "="); ok && isRuleName(strings.TrimSpace(name)) {
+			set, err := p.parseAttributes(strings.TrimSpace(attrs))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			p.sets[strings.TrimSpace(name)] = set
+			continue
+		}
+
+		// !pattern
+		if strings.HasPrefix(line, "!") {
+			pattern := strings.TrimSpace(line[1:])
+			if pattern == "" {
+				return nil, fmt.Errorf("line %d: missing pattern after '!'", lineNo)
+			}
+			p.rules = append(p.rules, policyRule{pattern: pattern, rules: RuleSet{Ignore: true}})
+			continue
+		}
+
+		// pattern attrs
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected '<pattern> <rules>', 'NAME = <rules>' or '!<pattern>'", lineNo)
+		}
+		set, err := p.parseAttributes(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		p.rules = append(p.rules, policyRule{pattern: fields[0], rules: set})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Policy) parseAttributes(spec string) (RuleSet, error) {
+	var set RuleSet
+	if spec == "" {
+		return set, fmt.Errorf("empty rule set")
+	}
+	for _, attr := range strings.Split(spec, "+") {
+		attr = strings.TrimSpace(attr)
+		if named, ok := p.sets[attr]; ok {
+			set = mergeRuleSets(set, named)
+			continue
+		}
+		switch strings.ToLower(attr) {
+		case "hash", "content":
+			set.Hash = true
+		case "size", "s":
+			set.Size = true
+		case "perms", "mode", "p":
+			set.Perms = true
+		case "owner", "u", "g":
+			set.Owner = true
+		case "mtime", "m":
+			set.MTime = true
+		case "inode", "i":
+			set.Inode = true
+		case "growing", "log":
+			set.Growing = true
+		case "ignore":
+			set.Ignore = true
+		default:
+			if err := checkAlgorithm(attr); err != nil {
+				return set, fmt.Errorf("unknown attribute or rule set %q", attr)
+			}
+			set.Hash = true
+			set.Algorithm = strings.ToLower(attr)
+		}
+	}
+	return set, nil
+}
+
+func mergeRuleSets(a, b RuleSet) RuleSet {
+	if b.Algorithm != "" {
+		a.Algorithm = b.Algorithm
+	}
+	a.Hash = a.Hash || b.Hash
+	a.Size = a.Size || b.Size
+	a.Perms = a.Perms || b.Perms
+	a.Owner = a.Owner || b.Owner
+	a.MTime = a.MTime || b.MTime
+	a.Inode = a.Inode || b.Inode
+	a.Growing = a.Growing || b.Growing
+	a.Ignore = a.Ignore || b.Ignore
+	return a
+}
+
+func isRuleName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z'):
+		case i > 0 && (r == '-' || (r >= '0' && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RuleFor returns the rule set of the root-relative path rel: the last
+// matching line, or the DEFAULT set when no line matches.
+func (p *Policy) RuleFor(rel string) RuleSet {
+	rel = NormalizePath(rel)
+	for i := len(p.rules) - 1; i >= 0; i-- {
+		if MatchGlob(strings.TrimPrefix(p.rules[i].pattern, "/"), rel) {
+			return p.rules[i].rules
+		}
+	}
+	return p.sets[DefaultRuleName]
+}
+
+// ruleFunc returns the rule lookup used when comparing results whose paths are
+// relative to root (or already root-relative when root is empty). A nil policy
+// checks everything.
+func (p *Policy) ruleFunc(root string) func(string) RuleSet {
+	return func(path string) RuleSet {
+		if p == nil {
+			return allAttributes
+		}
+		if root != "" {
+			path = rebasePath(path, root)
+		}
+		return p.RuleFor(path)
+	}
+}
+
+// algorithmFor returns the algorithm to hash rel with, falling back to hashType.
+func (p *Policy) algorithmFor(rel, hashType string) string {
+	if p == nil {
+		return hashType
+	}
+	if alg := p.RuleFor(rel).Algorithm; alg != "" {
+		return alg
+	}
+	return hashType
+}
+
+// ignores reports whether rel is excluded by the policy.
+func (p *Policy) ignores(rel string) bool {
+	return p != nil && p.RuleFor(rel).Ignore
+}
+
+// policyFingerprint is the resolved form of a policy: the rule lines in order
+// and the DEFAULT set.
+type policyFingerprint struct {
+	Patterns []string  `json:"patterns"`
+	Rules    []RuleSet `json:"rules"`
+	Default  RuleSet   `json:"default"`
+}
+
+// fingerprint returns the resolved rules of p, or nil for a nil policy.
+func (p *Policy) fingerprint() *policyFingerprint {
+	if p == nil {
+		return nil
+	}
+	f := &policyFingerprint{Default: p.sets[DefaultRuleName]}
+	for _, r := range p.rules {
+		f.Patterns = append(f.Patterns, r.pattern)
+		f.Rules = append(f.Rules, r.rules)
+	}
+	return f
+}
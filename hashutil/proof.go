@@ -0,0 +1,134 @@
+package hashutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrProofMismatch is returned (wrapped) by VerifyMerkleProof when the file and
+// proof do not lead to the expected root.
+var ErrProofMismatch = errors.New("inclusion proof does not match the root")
+
+// MerkleProof proves that a file with a given content digest is part of a
+// directory tree with a given Merkle root. Levels run from the file's directory
+// up to the root; each lists the other entries of that directory, which is all
+// a verifier needs to recompute the directory hashes.
+type MerkleProof struct {
+	Version  int          `json:"version"`
+	HashType string       `json:"hash_type"`
+	Modes    bool         `json:"modes,omitempty"`
+	Path     string       `json:"path"`
+	Mode     string       `json:"mode,omitempty"`
+	Root     string       `json:"root"`
+	Levels   []ProofLevel `json:"levels"`
+}
+
+// ProofLevel holds the siblings of the path component at one directory level.
+type ProofLevel struct {
+	Siblings []ProofEntry `json:"siblings"`
+}
+
+// ProofEntry is a directory entry as it enters the parent hash.
+type ProofEntry struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Hash string `json:"hash"`
+}
+
+// BuildMerkleProof builds the tree of results (see BuildMerkleTree) and returns
+// the inclusion proof of the file at the root-relative path rel.
+func BuildMerkleProof(results []HashResult, rel, hashType string, opts MerkleOptions) (MerkleProof, error) {
+	tree, err := BuildMerkleTree(results, hashType, opts)
+	if err != nil {
+		return MerkleProof{}, err
+	}
+
+	rel = NormalizePath(rel)
+	leaf := tree.Find(rel)
+	if leaf == nil || leaf.Dir {
+		return MerkleProof{}, fmt.Errorf("%s: no such file in the tree", rel)
+	}
+
+	proof := MerkleProof{
+		Version:  1,
+		HashType: strings.ToUpper(hashType),
+		Modes:    opts.Modes,
+		Path:     rel,
+		Mode:     leaf.Mode,
+		Root:     tree.Hash,
+	}
+	node := tree
+	for _, part := range strings.Split(rel, "/") {
+		level := ProofLevel{Siblings: []ProofEntry{}}
+		for _, c := range node.Children {
+			if c.Name != part {
+				level.Siblings = append(level.Siblings, ProofEntry{Name: c.Name, Dir: c.Dir, Mode: c.Mode, Hash: c.Hash})
+			}
+		}
+		// Levels are stored from the file upwards.
+		proof.Levels = append([]ProofLevel{level}, proof.Levels...)
+		node = node.child(part)
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that a file whose content digest (computed with
+// proof.HashType) is contentDigest belongs to the tree with the given root.
+// The root must come from a trusted source; proof.Root is only informational.
+func VerifyMerkleProof(proof MerkleProof, contentDigest, root string) error {
+	return VerifyKeyedMerkleProof(proof, contentDigest, root, nil)
+}
+
+// VerifyKeyedMerkleProof is like VerifyMerkleProof but a keyed proof.HashType uses key.
+func VerifyKeyedMerkleProof(proof MerkleProof, contentDigest, root string, key []byte) error {
+	hashType := strings.ToLower(proof.HashType)
+	parts := strings.Split(NormalizePath(proof.Path), "/")
+	if proof.Path == "" || path.IsAbs(proof.Path) || len(parts) != len(proof.Levels) {
+		return fmt.Errorf("malformed proof for %q: %d level(s)", proof.Path, len(proof.Levels))
+	}
+	content, err := hex.DecodeString(contentDigest)
+	if err != nil {
+		return fmt.Errorf("invalid digest: %w", err)
+	}
+
+	h, err := GetKeyedHasher(hashType, key)
+	if err != nil {
+		return err
+	}
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(content)
+	current := &MerkleNode{Name: parts[len(parts)-1], Hash: hex.EncodeToString(h.Sum(nil))}
+	if proof.Modes {
+		current.Mode = proof.Mode
+	}
+
+	for i, level := range proof.Levels {
+		entries := []*MerkleNode{current}
+		for _, s := range level.Siblings {
+			if s.Name == current.Name {
+				return fmt.Errorf("malformed proof: duplicate entry %s", s.Name)
+			}
+			entries = append(entries, &MerkleNode{Name: s.Name, Path: s.Name, Dir: s.Dir, Mode: s.Mode, Hash: s.Hash})
+		}
+		sort.Slice(entries, func(a, b int) bool { return entries[a].Name < entries[b].Name })
+		dirHash, err := merkleDirHash(entries, hashType, key)
+		if err != nil {
+			return err
+		}
+		name := "."
+		if next := len(parts) - 2 - i; next >= 0 {
+			name = parts[next]
+		}
+		current = &MerkleNode{Name: name, Dir: true, Hash: dirHash}
+	}
+
+	if !HashEqual(current.Hash, root) {
+		return fmt.Errorf("%w: computed %s, expected %s", ErrProofMismatch, current.Hash, root)
+	}
+	return nil
+}
@@ -0,0 +1,484 @@
+package hashutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// VerifyEntry describes the outcome for a single file in a directory verification.
+type VerifyEntry struct {
+	FilePath string `json:"file_path"`
+	HashType string `json:"hash_type,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Changes lists metadata differences such as "mode: -rw-r--r-- -> -rwxr-xr-x".
+	Changes []string `json:"changes,omitempty"`
+	// From is the reference path a renamed or copied file has the content of.
+	From string `json:"from,omitempty"`
+
+	// digests holds every digest of a new file, so pairMoves can match it
+	// against entries recorded with another algorithm.
+	digests []Digest
+}
+
+// VerifyReport is the structured result of comparing a directory against a reference.
+type VerifyReport struct {
+	// Interrupted is set when verification was cancelled before every file was hashed.
+	// Missing is left empty in that case because absence cannot be decided.
+	Interrupted bool `json:"interrupted,omitempty"`
+
+	Matched    []VerifyEntry `json:"matched"`
+	Mismatched []VerifyEntry `json:"mismatched"`
+	// MetaChanged holds files whose content is unchanged but whose recorded
+	// metadata (size, mode, owner, mtime, ...) differs.
+	MetaChanged []VerifyEntry `json:"meta_changed"`
+	Missing     []VerifyEntry `json:"missing"`
+	Extra       []VerifyEntry `json:"extra"`
+	// Renamed holds new files whose digest matches a missing reference entry,
+	// i.e. files that were moved. When one missing file reappears several
+	// times, the first new path is the rename and the others are copies.
+	Renamed []VerifyEntry `json:"renamed"`
+	// Copied holds new files whose digest matches an unchanged reference entry
+	// (or a renamed one, see Renamed).
+	Copied     []VerifyEntry `json:"copied"`
+	Unreadable []VerifyEntry `json:"unreadable"`
+}
+
+// OK reports whether every reference entry was found on disk unchanged and nothing else was found.
+func (r VerifyReport) OK() bool {
+	return !r.Interrupted && len(r.Mismatched) == 0 && len(r.MetaChanged) == 0 && len(r.Missing) == 0 &&
+		len(r.Extra) == 0 && len(r.Renamed) == 0 && len(r.Copied) == 0 && len(r.Unreadable) == 0
+}
+
+// Total returns the number of entries across all categories.
+func (r VerifyReport) Total() int {
+	return len(r.Matched) + len(r.Mismatched) + len(r.MetaChanged) + len(r.Missing) + len(r.Extra) +
+		len(r.Renamed) + len(r.Copied) + len(r.Unreadable)
+}
+
+// MarkUnreadable records a file that could not be hashed. If the file is listed in
+// the reference it is moved out of Missing so it is only reported once. A file
+// that was taken for renamed from it is a copy, since the original still exists.
+func (r *VerifyReport) MarkUnreadable(path string, err error) {
+	entry := VerifyEntry{FilePath: path}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	key := NormalizePath(path)
+	for i, m := range r.Missing {
+		if NormalizePath(m.FilePath) == key {
+			entry.HashType = m.HashType
+			entry.Expected = m.Expected
+			r.Missing = append(r.Missing[:i], r.Missing[i+1:]...)
+			break
+		}
+	}
+	for i, m := range r.Renamed {
+		if NormalizePath(m.From) == key {
+			entry.HashType = m.HashType
+			entry.Expected = m.Expected
+			r.Renamed = append(r.Renamed[:i], r.Renamed[i+1:]...)
+			r.Copied = append(r.Copied, m)
+			sortEntries(r.Copied)
+			break
+		}
+	}
+	r.Unreadable = append(r.Unreadable, entry)
+	sortEntries(r.Unreadable)
+}
+
+// newVerifyReport returns a report with empty (non-nil) categories so it
+// serializes as [] instead of null.
+func newVerifyReport() VerifyReport {
+	return VerifyReport{
+		Matched:     []VerifyEntry{},
+		Mismatched:  []VerifyEntry{},
+		MetaChanged: []VerifyEntry{},
+		Missing:     []VerifyEntry{},
+		Extra:       []VerifyEntry{},
+		Renamed:     []VerifyEntry{},
+		Copied:      []VerifyEntry{},
+		Unreadable:  []VerifyEntry{},
+	}
+}
+
+func sortEntries(entries []VerifyEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].FilePath < entries[j].FilePath
+	})
+}
+
+// CompareResults membandingkan hash hasil saat ini dengan referensi secara dua arah
+// dan mengembalikan laporan terstruktur.
+func CompareResults(actual, reference []HashResult) VerifyReport {
+	return CompareResultsWithPolicy(actual, reference, nil)
+}
+
+// CompareResultsWithPolicy is like CompareResults but only checks the attributes
+// the policy selects for each file. Paths are matched against the policy as
+// they appear in the results, so they should be relative to the policy root.
+// A nil policy checks the content and every recorded attribute.
+func CompareResultsWithPolicy(actual, reference []HashResult, policy *Policy) VerifyReport {
+	report := compareResults(actual, reference, policy.ruleFunc(""))
+	report.pairMoves()
+	return report
+}
+
+func compareResults(actual, reference []HashResult, ruleFor func(string) RuleSet) VerifyReport {
+	referenceMap := make(map[string]HashResult)
+	for _, ref := range reference {
+		// Normalisasi path (slash, dot segment) supaya ./a/b dan a/b dianggap sama
+		referenceMap[NormalizePath(ref.FilePath)] = ref
+	}
+
+	report := newVerifyReport()
+	seen := make(map[string]bool, len(actual))
+	for _, a := range actual {
+		key := NormalizePath(a.FilePath)
+		seen[key] = true
+
+		ref, found := referenceMap[key]
+		if !found {
+			report.Extra = append(report.Extra, VerifyEntry{
+				FilePath: a.FilePath,
+				HashType: a.HashType,
+				Actual:   a.Hash,
+				digests:  a.AllDigests(),
+			})
+			continue
+		}
+
+		entry := VerifyEntry{
+			FilePath: a.FilePath,
+			HashType: ref.HashType,
+			Expected: ref.Hash,
+			Actual:   a.Hash,
+		}
+		if actualHash, ok := a.Digest(ref.HashType); ok {
+			entry.Actual = actualHash
+		}
+		rules := ruleFor(key)
+		entry.Changes = metaChanges(ref.Meta, a.Meta, rules)
+		switch {
+		case rules.Hash && !rules.Growing && !digestsMatch(a, ref):
+			report.Mismatched = append(report.Mismatched, entry)
+		case len(entry.Changes) > 0:
+			report.MetaChanged = append(report.MetaChanged, entry)
+		default:
+			report.Matched = append(report.Matched, entry)
+		}
+	}
+
+	for _, ref := range reference {
+		if !seen[NormalizePath(ref.FilePath)] {
+			report.Missing = append(report.Missing, VerifyEntry{
+				FilePath: ref.FilePath,
+				HashType: ref.HashType,
+				Expected: ref.Hash,
+			})
+		}
+	}
+
+	sortEntries(report.Matched)
+	sortEntries(report.Mismatched)
+	sortEntries(report.MetaChanged)
+	sortEntries(report.Missing)
+	sortEntries(report.Extra)
+	return report
+}
+
+// pairMoves moves new files whose digest matches a missing or unchanged
+// reference entry out of Extra (and the missing entry out of Missing) into
+// Renamed and Copied. Missing entries are paired in path order, with a new
+// file of the same name first. Every digest of a new file is considered, so
+// entries recorded with different algorithms still pair up.
+func (r *VerifyReport) pairMoves() {
+	extra := make(map[string][]int)
+	for i, e := range r.Extra {
+		for _, d := range e.allDigests() {
+			if key := digestKey(d.HashType, d.Hash); key != "" {
+				extra[key] = append(extra[key], i)
+			}
+		}
+	}
+	if len(extra) == 0 {
+		return
+	}
+	// Where the content of each digest can be copied from: an unchanged file,
+	// or else the original of a rename.
+	sources := make(map[string]string)
+	for _, e := range append(append([]VerifyEntry{}, r.Matched...), r.MetaChanged...) {
+		if key := digestKey(e.HashType, e.Expected); key != "" && sources[key] == "" {
+			sources[key] = e.FilePath
+		}
+	}
+
+	paired := make([]bool, len(r.Extra))
+	var missing []VerifyEntry
+	for _, m := range r.Missing {
+		key := digestKey(m.HashType, m.Expected)
+		var candidates []int
+		for _, c := range extra[key] {
+			if !paired[c] {
+				candidates = append(candidates, c)
+			}
+		}
+		if key == "" || len(candidates) == 0 {
+			missing = append(missing, m)
+			continue
+		}
+		// A move usually keeps the file name, so prefer a candidate with the same one.
+		pick := candidates[0]
+		for _, c := range candidates {
+			if path.Base(r.Extra[c].FilePath) == path.Base(m.FilePath) {
+				pick = c
+				break
+			}
+		}
+		paired[pick] = true
+		e := r.Extra[pick].in(key)
+		e.Expected, e.From = m.Expected, m.FilePath
+		r.Renamed = append(r.Renamed, e)
+		if sources[key] == "" {
+			sources[key] = m.FilePath
+		}
+	}
+
+	var rest []VerifyEntry
+	for i, e := range r.Extra {
+		if paired[i] {
+			continue
+		}
+		copied := false
+		for _, d := range e.allDigests() {
+			key := digestKey(d.HashType, d.Hash)
+			if from := sources[key]; key != "" && from != "" {
+				e = e.in(key)
+				e.Expected, e.From = e.Actual, from
+				r.Copied = append(r.Copied, e)
+				copied = true
+				break
+			}
+		}
+		if !copied {
+			rest = append(rest, e)
+		}
+	}
+	r.Missing = append([]VerifyEntry{}, missing...)
+	r.Extra = append([]VerifyEntry{}, rest...)
+	sortEntries(r.Renamed)
+	sortEntries(r.Copied)
+}
+
+func (e VerifyEntry) allDigests() []Digest {
+	if len(e.digests) > 0 {
+		return e.digests
+	}
+	return []Digest{{HashType: e.HashType, Hash: e.Actual}}
+}
+
+// in returns e with HashType and Actual set to its digest matching key.
+func (e VerifyEntry) in(key string) VerifyEntry {
+	for _, d := range e.allDigests() {
+		if digestKey(d.HashType, d.Hash) == key {
+			e.HashType, e.Actual = d.HashType, d.Hash
+			break
+		}
+	}
+	return e
+}
+
+func digestKey(hashType, hash string) string {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(hashType)) + ":" + hash
+}
+
+// digestsMatch compares every digest of ref that actual also carries (ignoring case).
+// When they share no algorithm the primary hashes are compared.
+func digestsMatch(actual, ref HashResult) bool {
+	compared := false
+	for _, d := range ref.AllDigests() {
+		got, ok := actual.Digest(d.HashType)
+		if !ok {
+			continue
+		}
+		compared = true
+		if !HashEqual(got, d.Hash) {
+			return false
+		}
+	}
+	if !compared {
+		return HashEqual(actual.Hash, ref.Hash)
+	}
+	return true
+}
+
+// ReferenceAlgorithms returns the comma separated list of algorithms needed to
+// verify reference, so every entry is checked with the algorithm it was recorded
+// with. fallback is used for entries that carry no hash type and is listed first
+// in that case so it becomes the primary digest.
+func ReferenceAlgorithms(reference []HashResult, fallback string) (string, error) {
+	var typed []string
+	needFallback := len(reference) == 0
+	for _, ref := range reference {
+		for _, d := range ref.AllDigests() {
+			if strings.TrimSpace(d.HashType) == "" {
+				needFallback = true
+				continue
+			}
+			if err := checkAlgorithm(strings.TrimSpace(d.HashType)); err != nil {
+				return "", fmt.Errorf("reference entry %s: %w", ref.FilePath, err)
+			}
+			typed = append(typed, strings.TrimSpace(d.HashType))
+		}
+	}
+
+	var algs []string
+	if needFallback {
+		algs = append(algs, fallback)
+	}
+	algs = append(algs, typed...)
+	parsed, err := ParseAlgorithms(strings.Join(algs, ","))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(parsed, ","), nil
+}
+
+// VerifyDirectory hashes dirPath and compares it against reference. Each file is
+// hashed with the algorithm recorded in its reference entry; hashType is only used
+// for entries without one. Files that cannot be read are reported as Unreadable
+// instead of Missing.
+func VerifyDirectory(dirPath, hashType string, reference []HashResult, opts DirOptions) (VerifyReport, error) {
+	return VerifyDirectoryContext(context.Background(), dirPath, hashType, reference, opts)
+}
+
+// VerifyDirectoryContext is like VerifyDirectory but can be cancelled through ctx.
+// On cancellation the partial report is returned with Interrupted set, along with ctx.Err().
+func VerifyDirectoryContext(ctx context.Context, dirPath, hashType string, reference []HashResult, opts DirOptions) (VerifyReport, error) {
+	type failure struct {
+		path string
+		err  error
+	}
+	var failures []failure
+
+	cp := opts.Checkpoint
+	if cp != nil {
+		opts.OwnFiles = append(append([]string(nil), opts.OwnFiles...), cp.Path())
+	}
+
+	// Entries the walk would skip are not expected on disk.
+	filter, err := newPathFilter(dirPath, opts.Filter)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	own := newOwnFiles(dirPath, opts.OwnFiles)
+	var expected []HashResult
+	for _, ref := range reference {
+		rel := ref.FilePath
+		if !opts.RelativePaths {
+			rel = rebasePath(rel, dirPath)
+		}
+		if !filter.excludes(rel) && !opts.Policy.ignores(rel) && !own.match(rel) {
+			expected = append(expected, ref)
+		}
+	}
+	reference = expected
+
+	algs, err := ReferenceAlgorithms(reference, hashType)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	// A file is only hashed with the algorithms of its own reference entry.
+	// Files without one get all of them, so moves can still be paired.
+	opts.algorithms = make(map[string]string, len(reference))
+	for _, ref := range reference {
+		alg, err := ReferenceAlgorithms([]HashResult{ref}, hashType)
+		if err != nil {
+			return VerifyReport{}, err
+		}
+		opts.algorithms[NormalizePath(ref.FilePath)] = alg
+	}
+	for _, ref := range reference {
+		if ref.Meta != nil || opts.Policy != nil {
+			opts.Metadata = true
+			break
+		}
+	}
+
+	var onResult func(HashResult)
+	var saveErr error
+	if cp != nil {
+		if err := cp.bind(verifyFingerprint(dirPath, algs, reference, opts)); err != nil {
+			return VerifyReport{}, err
+		}
+		// The walk runs concurrently with onResult, so it gets its own copy.
+		opts.skip = cp.doneSnapshot()
+		opts.Discard = true
+		onResult = func(r HashResult) {
+			if err := cp.addResult(r); err != nil && saveErr == nil {
+				saveErr = err
+			}
+		}
+	}
+
+	actual, err := GenerateDirHashContext(ctx, dirPath, algs, opts, onResult, func(path string, err error) {
+		// Paranoid cache warnings are redundant here: the fresh digest is
+		// compared against the reference anyway.
+		var mismatch *CacheMismatchError
+		if errors.As(err, &mismatch) {
+			return
+		}
+		if cp != nil {
+			if err := cp.addFailure(path, err); err != nil && saveErr == nil {
+				saveErr = err
+			}
+			return
+		}
+		failures = append(failures, failure{path, err})
+	})
+	interrupted := err != nil && ctx.Err() != nil
+	if err != nil && !interrupted {
+		return VerifyReport{}, err
+	}
+
+	if cp != nil {
+		if err := cp.Save(); err != nil && saveErr == nil {
+			saveErr = err
+		}
+		if saveErr != nil {
+			return VerifyReport{}, fmt.Errorf("saving checkpoint %s: %w", cp.Path(), saveErr)
+		}
+		// Outcomes from earlier runs and this one, as if it had never stopped.
+		actual = cp.state.Results
+		for _, f := range cp.state.Failures {
+			failures = append(failures, failure{f.Path, errors.New(f.Error)})
+		}
+	}
+
+	root := ""
+	if !opts.RelativePaths {
+		root = dirPath
+	}
+	report := compareResults(actual, reference, opts.Policy.ruleFunc(root))
+	for _, f := range failures {
+		report.MarkUnreadable(f.path, f.err)
+	}
+	if interrupted {
+		report.Interrupted = true
+		report.Missing = []VerifyEntry{}
+		report.pairMoves()
+		return report, err
+	}
+	report.pairMoves()
+	return report, nil
+}
@@ -0,0 +1,285 @@
+package hashutil
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureExt is appended to a manifest name to find its detached signature.
+const SignatureExt = ".sig"
+
+// signedManifestFormat identifies an embedded-signature envelope.
+const signedManifestFormat = "catmint-signed-manifest"
+
+// signedManifestVersion 2 signs the manifest name along with its bytes; in
+// version 1 the name, which picks the parser, could be changed unnoticed.
+const signedManifestVersion = 2
+
+var (
+	// ErrBadSignature is returned (wrapped) when a manifest signature does not
+	// validate against the configured public key.
+	ErrBadSignature = errors.New("manifest signature is invalid")
+
+	// ErrSignedManifest is returned by LoadHashReference for a signed envelope,
+	// which must be loaded with LoadSignedReference and a public key.
+	ErrSignedManifest = errors.New("manifest is signed: a public key is required to verify it")
+)
+
+// SignedManifest is the embedded-signature envelope written by `catmint sign -embed`.
+// The signature covers ManifestName and the exact bytes of Manifest.
+type SignedManifest struct {
+	Format       string `json:"format"`
+	Version      int    `json:"version"`
+	Algorithm    string `json:"algorithm"`
+	KeyID        string `json:"key_id"`
+	Signature    string `json:"signature"`
+	ManifestName string `json:"manifest_name"`
+	Manifest     []byte `json:"manifest"`
+}
+
+// GenerateKeyPair writes a new Ed25519 key pair as PEM (PKCS#8 private key
+// readable only by the owner, PKIX public key), compatible with openssl.
+func GenerateKeyPair(privPath, pubPath string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	if err := writeNewFile(privPath, privPEM, 0600); err != nil {
+		return nil, err
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	if err := writeNewFile(pubPath, pubPEM, 0644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// writeNewFile refuses to overwrite an existing key.
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS#8 Ed25519 private key.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX Ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s: no PEM %q block found", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// KeyID returns a short fingerprint of pub, used to tell keys apart.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// FormatSignature renders a detached signature file: "ed25519 <key id> <base64 signature>".
+func FormatSignature(data []byte, priv ed25519.PrivateKey) string {
+	sig := ed25519.Sign(priv, data)
+	pub := priv.Public().(ed25519.PublicKey)
+	return fmt.Sprintf("ed25519 %s %s\n", KeyID(pub), base64.StdEncoding.EncodeToString(sig))
+}
+
+// VerifySignature checks a detached signature file's content against data.
+func VerifySignature(data, sigFile []byte, pub ed25519.PublicKey) error {
+	fields := strings.Fields(string(sigFile))
+	if len(fields) != 3 || fields[0] != "ed25519" {
+		return fmt.Errorf("%w: malformed signature file", ErrBadSignature)
+	}
+	return checkSignature(data, fields[1], fields[2], pub)
+}
+
+func checkSignature(data []byte, keyID, encoded string, pub ed25519.PublicKey) error {
+	if keyID != KeyID(pub) {
+		return fmt.Errorf("%w: signed by key %s, expected %s", ErrBadSignature, keyID, KeyID(pub))
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || !ed25519.Verify(pub, data, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// NewSignedManifest wraps manifest data read from a file called name in a signed envelope.
+func NewSignedManifest(name string, data []byte, priv ed25519.PrivateKey) SignedManifest {
+	pub := priv.Public().(ed25519.PublicKey)
+	name = filepath.Base(name)
+	return SignedManifest{
+		Format:       signedManifestFormat,
+		Version:      signedManifestVersion,
+		Algorithm:    "ed25519",
+		KeyID:        KeyID(pub),
+		Signature:    base64.StdEncoding.EncodeToString(ed25519.Sign(priv, signedPayload(name, data))),
+		ManifestName: name,
+		Manifest:     data,
+	}
+}
+
+// signedPayload is what an envelope signs: the format, the length-prefixed
+// manifest name and the manifest bytes.
+func signedPayload(name string, data []byte) []byte {
+	payload := []byte(signedManifestFormat + "\x00")
+	payload = binary.AppendUvarint(payload, uint64(len(name)))
+	payload = append(payload, name...)
+	return append(payload, data...)
+}
+
+// Open verifies the envelope against pub and returns the embedded manifest bytes.
+func (m SignedManifest) Open(pub ed25519.PublicKey) ([]byte, error) {
+	if m.Format != signedManifestFormat || m.Algorithm != "ed25519" {
+		return nil, fmt.Errorf("unsupported signed manifest (format %q, algorithm %q)", m.Format, m.Algorithm)
+	}
+	if m.Version != signedManifestVersion {
+		return nil, fmt.Errorf("unsupported signed manifest version %d; sign the manifest again", m.Version)
+	}
+	if err := checkSignature(signedPayload(m.ManifestName, m.Manifest), m.KeyID, m.Signature, pub); err != nil {
+		return nil, err
+	}
+	return m.Manifest, nil
+}
+
+func isSignedManifest(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+	var probe struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(trimmed, &probe) == nil && probe.Format == signedManifestFormat
+}
+
+// LoadSignedReference loads a reference only if its signature validates
+// against pub. An embedded-signature envelope is verified directly; any other
+// manifest needs its detached signature at sigPath (default: path + ".sig").
+func LoadSignedReference(path, sigPath string, pub ed25519.PublicKey) ([]HashResult, error) {
+	m, err := LoadSignedManifest(path, sigPath, pub)
+	if err != nil {
+		return nil, err
+	}
+	return m.Entries, nil
+}
+
+// LoadSignedManifest is like LoadSignedReference but also returns the header.
+func LoadSignedManifest(path, sigPath string, pub ed25519.PublicKey) (Manifest, error) {
+	data, name, err := openSigned(path, sigPath, pub)
+	if err != nil {
+		return Manifest{}, err
+	}
+	return ParseManifest(data, name)
+}
+
+// LoadSignedChecksumFile is like LoadChecksumFile but, like LoadSignedReference,
+// only trusts path if its signature validates against pub.
+func LoadSignedChecksumFile(path, sigPath string, pub ed25519.PublicKey) (entries []HashResult, bad int, err error) {
+	data, name, err := openSigned(path, sigPath, pub)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseChecksumFile(data, name)
+}
+
+// openSigned returns the verified content of path and the file name that
+// selects its parser.
+func openSigned(path, sigPath string, pub ed25519.PublicKey) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if isSignedManifest(data) {
+		var envelope SignedManifest
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, "", err
+		}
+		manifest, err := envelope.Open(pub)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, err)
+		}
+		return manifest, envelope.ManifestName, nil
+	}
+
+	if sigPath == "" {
+		sigPath = path + SignatureExt
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := VerifySignature(data, sig, pub); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if !isReferenceFileName(path) {
+		return nil, "", fmt.Errorf("format referensi tidak didukung: %s", filepath.Ext(path))
+	}
+	return data, path, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package hashutil
+
+import "os"
+
+// sysStat extracts the platform specific parts of info.
+func sysStat(info os.FileInfo) (fileSys, bool) {
+	return fileSys{}, false
+}
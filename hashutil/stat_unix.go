@@ -0,0 +1,23 @@
+//go:build unix
+
+package hashutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// sysStat extracts the platform specific parts of info.
+func sysStat(info os.FileInfo) (fileSys, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileSys{}, false
+	}
+	return fileSys{
+		Dev:   uint64(st.Dev),
+		Inode: uint64(st.Ino),
+		Nlink: uint64(st.Nlink),
+		UID:   st.Uid,
+		GID:   st.Gid,
+	}, true
+}
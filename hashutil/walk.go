@@ -0,0 +1,379 @@
+package hashutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DirOptions controls how GenerateDirHashWithOptions walks and hashes a directory.
+type DirOptions struct {
+	// Workers is the number of files hashed concurrently. Zero means runtime.NumCPU().
+	Workers int
+
+	// RelativePaths records paths relative to the hashed root with forward slashes
+	// instead of the raw walk path, so the manifest stays valid wherever the tree
+	// is mounted. The same relative path is passed to onError.
+	RelativePaths bool
+
+	// Key is the key of the keyed algorithms (hmac-<alg>, blake3-keyed).
+	Key []byte
+
+	// Filter selects the files to hash. When nil every file is hashed, except
+	// those ignored by <root>/.catmintignore.
+	Filter *Filter
+
+	// Cache, when set, is consulted before reading a file and refreshed after
+	// hashing it. Saving it is left to the caller.
+	Cache *Cache
+	// Rehash ignores cached digests but still refreshes the cache.
+	Rehash bool
+	// Paranoid rehashes every file and reports a *CacheMismatchError to onError
+	// (before onResult) when the content differs from a cache entry whose
+	// metadata still matches.
+	Paranoid bool
+
+	// Metadata records size, mode, owner, mtime, inode, link count and symlink
+	// target in HashResult.Meta.
+	Metadata bool
+
+	// Discard only delivers results to onResult and returns no slice, so memory
+	// does not grow with the number of files.
+	Discard bool
+
+	// Checkpoint, when set, records the outcome of every file in
+	// VerifyDirectoryContext and skips the files it already holds.
+	Checkpoint *Checkpoint
+
+	// OwnFiles lists files written while the tree is walked, such as the
+	// output manifest or a checkpoint. They and their temporary files
+	// (<name>.tmp-* and .<name>.tmp-*) are never hashed.
+	OwnFiles []string
+
+	// skip holds names (as reported in HashResult.FilePath) not to hash again.
+	skip map[string]bool
+	// only, when set, holds the only names to hash.
+	only map[string]bool
+	// algorithms, when set, holds the algorithms to hash a name with, ahead
+	// of Policy; other names use hashType.
+	algorithms map[string]string
+
+	// Policy, when set, skips the paths it ignores and picks the algorithm per
+	// file from its rule sets (hashType is used for rules without one).
+	// Metadata is always recorded so the policy attributes can be checked.
+	Policy *Policy
+}
+
+func (o DirOptions) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// name returns the FilePath recorded for path.
+func (o DirOptions) name(root, path string) string {
+	if o.RelativePaths {
+		return relativeName(root, path)
+	}
+	return path
+}
+
+// algorithmFor returns the algorithms to hash name with, falling back to hashType.
+func (o DirOptions) algorithmFor(name, hashType string) string {
+	if alg, ok := o.algorithms[NormalizePath(name)]; ok {
+		return alg
+	}
+	return hashType
+}
+
+type dirJob struct {
+	seq  int
+	path string
+	name string
+	alg  string
+	info os.FileInfo
+	err  error
+}
+
+type dirOutcome struct {
+	seq    int
+	path   string
+	result HashResult
+	warn   error
+	err    error
+}
+
+// GenerateDirHashWithOptions hashes every regular file below dirPath using a pool of
+// opts.Workers goroutines. Results and errors are delivered in walk order regardless
+// of which worker finishes first, and onResult/onError are never called concurrently.
+// At most a few results per worker are buffered while waiting for a slower file.
+func GenerateDirHashWithOptions(dirPath, hashType string, opts DirOptions, onResult func(HashResult), onError func(string, error)) ([]HashResult, error) {
+	return GenerateDirHashContext(context.Background(), dirPath, hashType, opts, onResult, onError)
+}
+
+// GenerateDirHashContext is like GenerateDirHashWithOptions but stops walking and
+// hashing once ctx is done. The results completed before cancellation are returned
+// together with ctx.Err(); files interrupted mid-read are not reported to onError.
+func GenerateDirHashContext(ctx context.Context, dirPath, hashType string, opts DirOptions, onResult func(HashResult), onError func(string, error)) ([]HashResult, error) {
+	if err := CheckKey(hashType, opts.Key); err != nil {
+		return nil, err
+	}
+	filter, err := newPathFilter(dirPath, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.workers()
+	jobs := make(chan dirJob)
+	outcomes := make(chan dirOutcome)
+	// Each slot is held from dispatch until the outcome is emitted in order,
+	// which bounds the reorder buffer.
+	slots := make(chan struct{}, workers*4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if j.err != nil {
+					outcomes <- dirOutcome{seq: j.seq, path: j.name, err: j.err}
+					continue
+				}
+				result, warn, err := hashDirFile(ctx, j.path, j.info, j.alg, opts)
+				result.FilePath = j.name
+				if (opts.Metadata || opts.Policy != nil) && err == nil && j.info != nil {
+					result.Meta = newFileMeta(j.path, j.info)
+				}
+				outcomes <- dirOutcome{seq: j.seq, path: j.name, result: result, warn: warn, err: err}
+			}
+		}()
+	}
+
+	var walkErr error
+	go func() {
+		seq := 0
+		dispatch := func(path string, info os.FileInfo, err error) error {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			name := opts.name(dirPath, path)
+			alg := opts.algorithmFor(name, opts.Policy.algorithmFor(relativeName(dirPath, path), hashType))
+			jobs <- dirJob{seq: seq, path: path, name: name, alg: alg, info: info, err: err}
+			seq++
+			return nil
+		}
+		walkErr = walkFiles(ctx, dirPath, filter, opts, dispatch)
+		close(jobs)
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var results []HashResult
+	pending := make(map[int]dirOutcome)
+	next := 0
+	for o := range outcomes {
+		pending[o.seq] = o
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-slots
+
+			if p.err != nil {
+				if ctx.Err() != nil && isContextError(p.err) {
+					continue
+				}
+				if onError != nil {
+					onError(p.path, p.err)
+				}
+				continue
+			}
+			if p.warn != nil && onError != nil {
+				onError(p.path, p.warn)
+			}
+			if onResult != nil {
+				onResult(p.result)
+			}
+			if !opts.Discard {
+				results = append(results, p.result)
+			}
+		}
+	}
+	if walkErr == nil {
+		walkErr = ctx.Err()
+	}
+	return results, walkErr
+}
+
+// walkFiles calls fn for every file below dirPath that filter, opts.Policy,
+// opts.skip and opts.only let through, and for every path that cannot be read.
+func walkFiles(ctx context.Context, dirPath string, filter *pathFilter, opts DirOptions, fn func(path string, info os.FileInfo, err error) error) error {
+	own := newOwnFiles(dirPath, opts.OwnFiles)
+	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if path != dirPath {
+			rel := relativeName(dirPath, path)
+			if info != nil && info.IsDir() {
+				if filter.skipDir(rel) || opts.Policy.ignores(rel) {
+					return filepath.SkipDir
+				}
+			} else if filter.skipFile(rel, info) || opts.Policy.ignores(rel) || own.match(rel) {
+				return nil
+			} else if opts.skip != nil && opts.skip[opts.name(dirPath, path)] {
+				return nil
+			} else if opts.only != nil && !opts.only[opts.name(dirPath, path)] {
+				return nil
+			}
+		}
+		if err != nil {
+			return fn(path, info, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return fn(path, info, nil)
+	})
+}
+
+// hashDirFile hashes one file of a directory walk, going through opts.Cache when set.
+// warn carries a *CacheMismatchError in paranoid mode.
+func hashDirFile(ctx context.Context, path string, info os.FileInfo, hashType string, opts DirOptions) (result HashResult, warn error, err error) {
+	// Keyed digests are not cached: they depend on a key the cache cannot check.
+	cache := opts.Cache
+	if cache == nil || hasKeyedAlgorithm(hashType) {
+		result, err = GenerateKeyedFileHash(ctx, path, hashType, opts.Key)
+		return result, nil, err
+	}
+
+	// The cache describes the content that is actually read, so follow symlinks.
+	if info == nil || info.Mode()&os.ModeSymlink != 0 {
+		if st, statErr := os.Stat(path); statErr == nil {
+			info = st
+		}
+	}
+	algs, err := ParseAlgorithms(hashType)
+	if err != nil {
+		return HashResult{}, nil, err
+	}
+
+	if !opts.Rehash && !opts.Paranoid && info != nil {
+		cached := HashResult{FilePath: path}
+		for _, alg := range algs {
+			hash, ok := cache.Lookup(path, info, alg)
+			if !ok {
+				break
+			}
+			cached.Digests = append(cached.Digests, Digest{HashType: strings.ToUpper(alg), Hash: hash})
+		}
+		if len(cached.Digests) == len(algs) {
+			cached.HashType, cached.Hash = cached.Digests[0].HashType, cached.Digests[0].Hash
+			if len(cached.Digests) == 1 {
+				cached.Digests = nil
+			}
+			return cached, nil, nil
+		}
+	}
+
+	result, err = GenerateKeyedFileHash(ctx, path, hashType, opts.Key)
+	if err != nil || info == nil {
+		return result, nil, err
+	}
+
+	// Only remember the digest if the file did not change while it was read.
+	after, statErr := os.Stat(path)
+	stable := statErr == nil && after.Size() == info.Size() && after.ModTime().Equal(info.ModTime())
+	for _, d := range result.AllDigests() {
+		if opts.Paranoid && warn == nil {
+			if entry, ok := cache.peek(path, info, d.HashType); ok && !strings.EqualFold(entry.Hash, d.Hash) {
+				warn = &CacheMismatchError{HashType: d.HashType, Cached: entry.Hash, Actual: d.Hash}
+			}
+		}
+		if stable {
+			cache.Store(path, info, d.HashType, d.Hash)
+		}
+	}
+	return result, warn, nil
+}
+
+func hasKeyedAlgorithm(hashType string) bool {
+	for _, alg := range strings.Split(hashType, ",") {
+		if IsKeyedAlgorithm(alg) {
+			return true
+		}
+	}
+	return false
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// ownFiles holds the relative names of DirOptions.OwnFiles inside a walked tree.
+type ownFiles map[string]bool
+
+// newOwnFiles resolves paths against root; paths outside the tree are dropped.
+func newOwnFiles(root string, paths []string) ownFiles {
+	if len(paths) == 0 {
+		return nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	own := make(ownFiles)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		own[NormalizePath(rel)] = true
+	}
+	return own
+}
+
+// match reports whether rel is one of the files or a temporary file of one.
+func (o ownFiles) match(rel string) bool {
+	if len(o) == 0 {
+		return false
+	}
+	if o[rel] {
+		return true
+	}
+	dir, base := path.Split(rel)
+	base = strings.TrimPrefix(base, ".")
+	if i := strings.LastIndex(base, ".tmp-"); i > 0 {
+		return o[dir+base[:i]]
+	}
+	return false
+}
+
+// relativeName returns path relative to root using forward slashes. When root
+// is itself the file being hashed its base name is used.
+func relativeName(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return NormalizePath(path)
+	}
+	if rel == "." {
+		return filepath.Base(path)
+	}
+	return NormalizePath(rel)
+}
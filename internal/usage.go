@@ -27,6 +27,13 @@ Usage:
 Commands:
   hash        Generate hash for a file or directory
   verify      Verify file hash or verify directory against reference file
+  cache       Inspect or prune the incremental hashing cache
+  keygen      Generate an Ed25519 key pair for signing manifests
+  sign        Sign a manifest (detached .sig or embedded JSON envelope)
+  proof       Emit or check a Merkle inclusion proof for a single file
+  diff        Compare two manifests: added, removed, modified and renamed files
+  compare     Hash two directory trees and compare them file by file
+  dupes       Find duplicate files, optionally hard link them or script their removal
   version     Show the version of the application
   help        Show this help message
   show-update Check for available updates
@@ -37,6 +44,14 @@ Global flags:
 
 Use "catmint <command> --help" to see available options.
 
+Exit codes:
+  0    success, everything verified
+  1    integrity failure (hash mismatch, missing or unexpected files, bad signature)
+  2    usage error (unknown command, invalid flags or arguments)
+  3    I/O error (a file, directory or the network could not be read or written)
+  4    parse error (reference/checksum file or response is malformed)
+  130  interrupted (SIGINT/SIGTERM)
+
 Examples:
   catmint hash -file test.txt -alg sha256 -o hash.txt
   catmint hash -dir ./myfolder -alg sha512 -o hash.json
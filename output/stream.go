@@ -0,0 +1,299 @@
+package output
+
+import (
+	"bufio"
+	"catmint/hashutil"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultFlushInterval is how often a ResultFile pushes buffered results to disk.
+const DefaultFlushInterval = 2 * time.Second
+
+// ResultWriter writes hash results one at a time as they arrive.
+// Close writes whatever the format needs after the last result.
+type ResultWriter interface {
+	Write(hashutil.HashResult) error
+	Flush() error
+	Close() error
+}
+
+// Options configures a ResultWriter.
+type Options struct {
+	// Meta adds the metadata columns to csv output. The header is written
+	// before the first result, so it has to be known up front.
+	Meta bool
+	// FlushInterval is how often a ResultFile flushes; zero means DefaultFlushInterval.
+	FlushInterval time.Duration
+	// Header turns json output into a manifest document and is written as
+	// comment lines before txt, csv and tsv output and as the first ndjson
+	// line. coreutils and bsd output stay plain so sha256sum -c can read them.
+	Header *hashutil.ManifestHeader
+}
+
+// NewResultWriter returns a writer that encodes results to w in format:
+// json (array), ndjson (one object per line), csv, tsv, txt, coreutils or bsd.
+func NewResultWriter(w io.Writer, format string, opts Options) (ResultWriter, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	sw := &streamWriter{buf: bufio.NewWriter(w), format: format, meta: opts.Meta, indent: "  "}
+	if opts.Header != nil {
+		if err := sw.writeHeader(opts.Header); err != nil {
+			return nil, err
+		}
+	}
+	if format == "csv" || format == "tsv" {
+		sw.csv = csv.NewWriter(sw.buf)
+		if format == "tsv" {
+			sw.csv.Comma = '\t'
+		}
+		header := []string{"File Path", "Hash Type", "Hash"}
+		if opts.Meta {
+			header = append(header, hashutil.MetaCSVHeader...)
+		}
+		if err := sw.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return sw, nil
+}
+
+type streamWriter struct {
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	format string
+	meta   bool
+	count  int
+	// indent is the json array indentation; doc is set when the array is
+	// the entries of a manifest document.
+	indent string
+	doc    bool
+}
+
+func (s *streamWriter) writeHeader(h *hashutil.ManifestHeader) error {
+	switch s.format {
+	case "json":
+		data, err := json.MarshalIndent(h, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.buf, "{\n  \"manifest_version\": %d,\n  \"header\": %s,\n  \"entries\": ", hashutil.ManifestVersion, data)
+		s.indent, s.doc = "    ", true
+	case "ndjson":
+		data, err := json.Marshal(struct {
+			Version int                      `json:"manifest_version"`
+			Header  *hashutil.ManifestHeader `json:"header"`
+		}{hashutil.ManifestVersion, h})
+		if err != nil {
+			return err
+		}
+		s.buf.Write(data)
+		s.buf.WriteByte('\n')
+	case "txt", "csv", "tsv":
+		for _, line := range h.CommentLines() {
+			s.buf.WriteString(line + "\n")
+		}
+	}
+	return nil
+}
+
+func (s *streamWriter) Write(r hashutil.HashResult) error {
+	defer func() { s.count++ }()
+	switch s.format {
+	case "json":
+		// Same layout as json.Encoder with two-space indent over the whole slice.
+		data, err := json.MarshalIndent(r, s.indent, "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n" + s.indent
+		if s.count == 0 {
+			sep = "[\n" + s.indent
+		}
+		s.buf.WriteString(sep)
+		_, err = s.buf.Write(data)
+		return err
+	case "ndjson":
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		s.buf.Write(data)
+		return s.buf.WriteByte('\n')
+	case "csv", "tsv":
+		for _, d := range r.AllDigests() {
+			row := []string{r.FilePath, d.HashType, d.Hash}
+			if s.meta {
+				row = append(row, hashutil.MetaCSVFields(r.Meta)...)
+			}
+			if err := s.csv.Write(row); err != nil {
+				return err
+			}
+		}
+	case "txt":
+		for i, d := range r.AllDigests() {
+			// metadata is written once, after the first digest of the file
+			if i == 0 && r.Meta != nil {
+				fmt.Fprintf(s.buf, "%s hash of file %s: %s %s\n", d.HashType, r.FilePath, d.Hash, hashutil.FormatMetaFields(r.Meta))
+				continue
+			}
+			fmt.Fprintf(s.buf, "%s hash of file %s: %s\n", d.HashType, r.FilePath, d.Hash)
+		}
+	case "coreutils":
+		// sha256sum style carries a single algorithm and cannot hold the others.
+		if len(r.Digests) > 1 {
+			return fmt.Errorf("%s: format coreutils hanya mendukung satu algoritma; gunakan bsd", r.FilePath)
+		}
+		fmt.Fprintln(s.buf, hashutil.FormatGNULine(r.Hash, r.FilePath))
+	case "bsd":
+		for _, d := range r.AllDigests() {
+			fmt.Fprintln(s.buf, hashutil.FormatBSDLine(d.HashType, r.FilePath, d.Hash))
+		}
+	}
+	return nil
+}
+
+func (s *streamWriter) Flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return s.buf.Flush()
+}
+
+func (s *streamWriter) Close() error {
+	if s.format == "json" {
+		end := "\n"
+		if s.doc {
+			end = "\n}\n"
+		}
+		if s.count == 0 {
+			s.buf.WriteString("[]" + end)
+		} else {
+			s.buf.WriteString("\n" + s.indent[2:] + "]" + end)
+		}
+	}
+	return s.Flush()
+}
+
+// ResultFile streams results into a temporary file next to the target and
+// renames it into place on Close, so readers never see a half-written manifest.
+// Results are flushed to the temporary file periodically, so a crash leaves
+// everything hashed so far on disk.
+type ResultFile struct {
+	ResultWriter
+	file      *os.File
+	path      string
+	interval  time.Duration
+	lastFlush time.Time
+	count     int
+}
+
+// CreateResultFile starts writing path in format. Call Close to finalize it or
+// Abort to discard it.
+func CreateResultFile(path, format string, opts Options) (*ResultFile, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewResultWriter(file, format, opts)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	interval := opts.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &ResultFile{ResultWriter: w, file: file, path: path, interval: interval, lastFlush: time.Now()}, nil
+}
+
+// Write adds one result, flushing when the flush interval has passed.
+func (f *ResultFile) Write(r hashutil.HashResult) error {
+	if err := f.ResultWriter.Write(r); err != nil {
+		return err
+	}
+	f.count++
+	if time.Since(f.lastFlush) >= f.interval {
+		f.lastFlush = time.Now()
+		return f.ResultWriter.Flush()
+	}
+	return nil
+}
+
+// Count returns the number of results written so far.
+func (f *ResultFile) Count() int {
+	return f.count
+}
+
+// TempPath returns the temporary file the results are written to until Close.
+func (f *ResultFile) TempPath() string {
+	return f.file.Name()
+}
+
+// Close finishes the format, syncs the temporary file and renames it over the target.
+func (f *ResultFile) Close() error {
+	return f.finish(f.path)
+}
+
+// ClosePartial is like Close for an interrupted run: the results go to
+// PartialPath instead, so a complete manifest at the target is never
+// replaced by an incomplete one.
+func (f *ResultFile) ClosePartial() error {
+	return f.finish(f.PartialPath())
+}
+
+// PartialPath returns where ClosePartial writes, e.g. hash.partial.json for
+// hash.json and SHA256SUMS.partial for SHA256SUMS.
+func (f *ResultFile) PartialPath() string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + ".partial" + ext
+}
+
+func (f *ResultFile) finish(path string) error {
+	if err := f.ResultWriter.Close(); err != nil {
+		f.Abort()
+		return err
+	}
+	// Keep the permissions of a file being replaced; CreateTemp uses 0600.
+	mode := os.FileMode(0644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	if err := f.file.Chmod(mode); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		os.Remove(f.file.Name())
+		return err
+	}
+	if err := os.Rename(f.file.Name(), path); err != nil {
+		os.Remove(f.file.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort discards the temporary file and leaves the target untouched.
+func (f *ResultFile) Abort() error {
+	f.file.Close()
+	return os.Remove(f.file.Name())
+}
@@ -2,37 +2,60 @@ package output
 
 import (
 	"catmint/hashutil"
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 )
 
+// SaveResultsToFile writes results to outputFile in one go. It is a thin wrapper
+// around CreateResultFile, so the file is replaced atomically.
 func SaveResultsToFile(results []hashutil.HashResult, outputFile, format string) error {
-	file, err := os.Create(outputFile)
+	w, err := CreateResultFile(outputFile, format, Options{Meta: hasMeta(results)})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	for _, r := range results {
+		if err := w.Write(r); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// SaveManifestToFile is like SaveResultsToFile but writes m.Header in front
+// of the entries (see Options.Header).
+func SaveManifestToFile(m hashutil.Manifest, outputFile, format string) error {
+	w, err := CreateResultFile(outputFile, format, Options{Meta: hasMeta(m.Entries), Header: m.Header})
+	if err != nil {
+		return err
+	}
+	for _, r := range m.Entries {
+		if err := w.Write(r); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Formats lists every format a ResultWriter can produce.
+var Formats = []string{"txt", "json", "ndjson", "csv", "tsv", "coreutils", "bsd"}
 
-	switch format {
-	case "json":
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(results)
-	case "csv":
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
-		writer.Write([]string{"File Path", "Hash Type", "Hash"})
-		for _, r := range results {
-			writer.Write([]string{r.FilePath, r.HashType, r.Hash})
+// checkFormat reports an error for formats no ResultWriter supports.
+func checkFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
 		}
-	case "txt":
-		for _, r := range results {
-			fmt.Fprintf(file, "%s hash of file %s: %s\n", r.HashType, r.FilePath, r.Hash)
+	}
+	return fmt.Errorf("unsupported format: %s (use %s)", format, strings.Join(Formats, ", "))
+}
+
+func hasMeta(results []hashutil.HashResult) bool {
+	for _, r := range results {
+		if r.Meta != nil {
+			return true
 		}
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
 	}
-	return nil
+	return false
 }